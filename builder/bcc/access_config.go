@@ -5,9 +5,14 @@ package bcc
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/baidubce/bce-sdk-go/bce"
 	"github.com/baidubce/bce-sdk-go/services/bcc"
+	"github.com/baidubce/bce-sdk-go/services/dcc"
 	"github.com/baidubce/bce-sdk-go/services/eip"
+	"github.com/baidubce/bce-sdk-go/services/eni"
+	"github.com/baidubce/bce-sdk-go/services/quotacenter"
 	"github.com/baidubce/bce-sdk-go/services/vpc"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 )
@@ -42,8 +47,15 @@ type BaiduCloudAccessConfig struct {
 	BaiduCloudRegion string `mapstructure:"region" required:"true"`
 	// The zone where your bcc instance will be launched. It must be set,
 	// unless  the field `use_default_network` is set true,
-	// which means you use the default network
+	// which means you use the default network, or `subnet_id` is set,
+	// in which case the zone of the subnet is used
 	Zone string `mapstructure:"zone" required:"true"`
+	// A prioritized list of zones to fall back to, if all the instance specs
+	// are sold out in `zone`. The temporary subnet will be recreated in the
+	// new zone. If `zone` is not set, the first zone of the list is used.
+	// The zone which is finally used is recorded in the artifact. The zones
+	// must belong to the region, and can't be duplicated
+	Zones []string `mapstructure:"zones" required:"false"`
 	// Do not check region and zone when validate
 	SkipValidation bool `mapstructure:"skip_region_validation" required:"false"`
 }
@@ -63,6 +75,26 @@ func (c *BaiduCloudAccessConfig) EipClient() (*eip.Client, error) {
 	return newEipClient(c.BaiduCloudAccessKey, c.BaiduCloudSecretKey, c.GetEipEndpoint())
 }
 
+// EniClient - create a client of baiducloud elastic network interface
+func (c *BaiduCloudAccessConfig) EniClient() (*eni.Client, error) {
+	return newEniClient(c.BaiduCloudAccessKey, c.BaiduCloudSecretKey, c.GetVpcEndpoint())
+}
+
+// DccClient - create a client of baiducloud dedicated host
+func (c *BaiduCloudAccessConfig) DccClient() (*dcc.Client, error) {
+	return newDccClient(c.BaiduCloudAccessKey, c.BaiduCloudSecretKey, c.GetBccEndpoint())
+}
+
+// QuotaCenterClient - create a client of baiducloud quota center
+func (c *BaiduCloudAccessConfig) QuotaCenterClient() (*quotacenter.Client, error) {
+	return newQuotaCenterClient(c.BaiduCloudAccessKey, c.BaiduCloudSecretKey)
+}
+
+// BillingClient - create a client of baiducloud billing service
+func (c *BaiduCloudAccessConfig) BillingClient() (bce.Client, error) {
+	return newBillingClient(c.BaiduCloudAccessKey, c.BaiduCloudSecretKey)
+}
+
 // ClientWithRegion - create a bcc client for specified region
 func (c *BaiduCloudAccessConfig) ClientWithRegion(region string) (*bcc.Client, error) {
 	return newBccClient(c.BaiduCloudAccessKey, c.BaiduCloudSecretKey, c.GetBccEndpointWithRegion(region))
@@ -86,6 +118,24 @@ func (c *BaiduCloudAccessConfig) Prepare(ctx *interpolate.Context) []error {
 		}
 	}
 
+	seen := map[string]bool{}
+	for _, zone := range c.Zones {
+		if seen[zone] {
+			errs = append(errs, fmt.Errorf("duplicate zone in 'zones': %s", zone))
+		}
+		seen[zone] = true
+	}
+	if c.Zone == "" && len(c.Zones) > 0 {
+		c.Zone = c.Zones[0]
+	}
+	if c.BaiduCloudRegion != "" && !c.SkipValidation {
+		for _, zone := range c.candidateZones() {
+			if err := validZone(c.BaiduCloudRegion, zone); zone != "" && err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
 	if len(errs) > 0 {
 		return errs
 	}
@@ -93,6 +143,20 @@ func (c *BaiduCloudAccessConfig) Prepare(ctx *interpolate.Context) []error {
 	return nil
 }
 
+// candidateZones returns `zone` followed by the other zones of `zones`
+func (c *BaiduCloudAccessConfig) candidateZones() []string {
+	zones := []string{c.Zone}
+	zoneSet := map[string]struct{}{c.Zone: {}}
+	for _, zone := range c.Zones {
+		if _, ok := zoneSet[zone]; ok || zone == "" {
+			continue
+		}
+		zoneSet[zone] = struct{}{}
+		zones = append(zones, zone)
+	}
+	return zones
+}
+
 func (c *BaiduCloudAccessConfig) Config() error {
 	if c.BaiduCloudAccessKey == "" {
 		c.BaiduCloudAccessKey = os.Getenv("BAIDUCLOUD_ACCESS_KEY")
@@ -136,3 +200,13 @@ func validRegion(region string) error {
 
 	return fmt.Errorf("unknown region: %s", region)
 }
+
+// validZone checks that the zone belongs to the region, whose zones are
+// named like cn-<region>-a
+func validZone(region, zone string) error {
+	prefix := fmt.Sprintf("cn-%s-", region)
+	if !strings.HasPrefix(zone, prefix) || len(zone) != len(prefix)+1 {
+		return fmt.Errorf("the zone(%s) doesn't belong to region(%s), it should be like %sa", zone, region, prefix)
+	}
+	return nil
+}
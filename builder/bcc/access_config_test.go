@@ -47,3 +47,44 @@ func TestBaiduCloudAccessConfigPrepare_Region(t *testing.T) {
 	}
 
 }
+
+func TestBaiduCloudAccessConfigPrepare_Zones(t *testing.T) {
+	c := getTestBaiduCloudAccessConfig()
+	c.BaiduCloudRegion = "fwh"
+
+	c.Zones = []string{"cn-fwh-b", "cn-fwh-a"}
+	if errs := c.Prepare(nil); errs != nil {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+	if c.Zone != "cn-fwh-b" {
+		t.Fatalf("Zone should be the first one of zones, got %s", c.Zone)
+	}
+	if zones := c.candidateZones(); len(zones) != 2 || zones[1] != "cn-fwh-a" {
+		t.Fatalf("Candidate zones are wrong: %v", zones)
+	}
+
+	c.Zone = "cn-fwh-c"
+	if zones := c.candidateZones(); len(zones) != 3 || zones[0] != "cn-fwh-c" {
+		t.Fatalf("Candidate zones should start with zone: %v", zones)
+	}
+
+	c = getTestBaiduCloudAccessConfig()
+	c.BaiduCloudRegion = "fwh"
+	c.Zones = []string{"cn-fwh-b", "cn-fwh-a", "cn-fwh-b"}
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error for the duplicate zone: %s", errs)
+	}
+
+	c = getTestBaiduCloudAccessConfig()
+	c.BaiduCloudRegion = "fwh"
+	c.Zone = "cn-bj-a"
+	c.Zones = []string{"cn-fwh-a", "cn-fwh-typo"}
+	if errs := c.Prepare(nil); len(errs) != 2 {
+		t.Fatalf("Should raise 2 errors for the zones of other regions: %s", errs)
+	}
+
+	c.SkipValidation = true
+	if errs := c.Prepare(nil); errs != nil {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+}
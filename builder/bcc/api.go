@@ -0,0 +1,233 @@
+package bcc
+
+import (
+	"encoding/json"
+
+	"github.com/baidubce/bce-sdk-go/bce"
+	"github.com/baidubce/bce-sdk-go/http"
+	"github.com/baidubce/bce-sdk-go/services/bcc"
+	"github.com/baidubce/bce-sdk-go/services/bcc/api"
+	"github.com/baidubce/bce-sdk-go/services/vpc"
+)
+
+// createInstanceBySpecArgs extends `api.CreateInstanceBySpecArgs` with the
+// fields which are accepted by the BCC API but not supported by the sdk yet
+type createInstanceBySpecArgs struct {
+	*api.CreateInstanceBySpecArgs
+	BidModel string `json:"bidModel,omitempty"`
+	BidPrice string `json:"bidPrice,omitempty"`
+
+	DedicatedHostId string `json:"dedicatedHostId,omitempty"`
+
+	RootDiskEncryptKey string `json:"rootDiskEncryptKey,omitempty"`
+
+	SecurityGroupIds           []string `json:"securityGroupIds,omitempty"`
+	EnterpriseSecurityGroupIds []string `json:"enterpriseSecurityGroupIds,omitempty"`
+
+	Ipv6 bool `json:"ipv6,omitempty"`
+}
+
+// createVPCArgs extends `vpc.CreateVPCArgs` with the ipv6 switch
+type createVPCArgs struct {
+	*vpc.CreateVPCArgs
+	EnableIpv6 bool `json:"enableIpv6,omitempty"`
+}
+
+// createSubnetArgs extends `vpc.CreateSubnetArgs` with the ipv6 switch
+type createSubnetArgs struct {
+	*vpc.CreateSubnetArgs
+	EnableIpv6 bool `json:"enableIpv6,omitempty"`
+}
+
+// createImageArgs extends `api.CreateImageArgs` with the kms key to encrypt
+// the image and its snapshots
+type createImageArgs struct {
+	*api.CreateImageArgs
+	EncryptKey string `json:"encryptKey,omitempty"`
+}
+
+// remoteCopyImageArgs extends `api.RemoteCopyImageArgs` with the kms key to
+// encrypt the copied images, which must belong to the destination region
+type remoteCopyImageArgs struct {
+	*api.RemoteCopyImageArgs
+	EncryptKey string `json:"encryptKey,omitempty"`
+}
+
+// enterpriseSecurityGroup is an enterprise security group, which is not
+// supported by the sdk yet
+type enterpriseSecurityGroup struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	Desc string `json:"desc"`
+}
+
+type listEnterpriseSecurityGroupsResult struct {
+	Marker                   string                    `json:"marker"`
+	IsTruncated              bool                      `json:"isTruncated"`
+	NextMarker               string                    `json:"nextMarker"`
+	MaxKeys                  int                       `json:"maxKeys"`
+	EnterpriseSecurityGroups []enterpriseSecurityGroup `json:"enterpriseSecurityGroups"`
+}
+
+// createInstanceBySpec works like `bcc.Client.CreateInstanceBySpec`, and sends
+// the extended fields as well. The args are left untouched, so that it's safe
+// to retry with the same args.
+func createInstanceBySpec(client *bcc.Client, args *createInstanceBySpecArgs) (*api.CreateInstanceBySpecResult, error) {
+	specArgs := *args.CreateInstanceBySpecArgs
+	if len(specArgs.AdminPass) > 0 {
+		cryptedPass, err := api.Aes128EncryptUseSecreteKey(client.Config.Credentials.SecretAccessKey, specArgs.AdminPass)
+		if err != nil {
+			return nil, err
+		}
+		specArgs.AdminPass = cryptedPass
+	}
+
+	body := *args
+	body.CreateInstanceBySpecArgs = &specArgs
+	jsonBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	reqBody, err := bce.NewBodyFromBytes(jsonBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return api.CreateInstanceBySpec(client, &specArgs, reqBody)
+}
+
+// rebuildInstanceArgs works like `api.RebuildInstanceArgs`, and omits the
+// empty admin password and keypair
+type rebuildInstanceArgs struct {
+	ImageId   string `json:"imageId"`
+	AdminPass string `json:"adminPass,omitempty"`
+	KeypairId string `json:"keypairId,omitempty"`
+}
+
+// rebuildInstance works like `bcc.Client.RebuildInstance`, but only encrypts
+// the admin password if it's set, so that the instance can be rebuilt with
+// the keypair alone. The args are left untouched, so that it's safe to retry
+// with the same args.
+func rebuildInstance(client *bcc.Client, instanceId string, args *rebuildInstanceArgs) error {
+	body := *args
+	if len(body.AdminPass) > 0 {
+		cryptedPass, err := api.Aes128EncryptUseSecreteKey(client.Config.Credentials.SecretAccessKey, body.AdminPass)
+		if err != nil {
+			return err
+		}
+		body.AdminPass = cryptedPass
+	}
+
+	params := map[string]string{"rebuild": ""}
+	return SendRequest(client, http.PUT, api.URI_PREFIXV2+api.REQUEST_INSTANCE_URI+"/"+instanceId, params, &body, nil)
+}
+
+// createImage works like `bcc.Client.CreateImage`, and sends the extended
+// fields as well
+func createImage(client *bcc.Client, args *createImageArgs) (*api.CreateImageResult, error) {
+	params := map[string]string{}
+	if args.ClientToken != "" {
+		params["clientToken"] = args.ClientToken
+	}
+
+	result := &api.CreateImageResult{}
+	err := SendRequest(client, http.POST, api.URI_PREFIXV2+api.REQUEST_IMAGE_URI, params, args, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// remoteCopyImage works like `bcc.Client.RemoteCopyImageReturnImageIds`, and
+// sends the extended fields as well
+func remoteCopyImage(client *bcc.Client, imageId string, args *remoteCopyImageArgs) (*api.RemoteCopyImageResult, error) {
+	params := map[string]string{"remoteCopy": ""}
+
+	result := &api.RemoteCopyImageResult{}
+	err := SendRequest(client, http.POST, api.URI_PREFIXV2+api.REQUEST_IMAGE_URI+"/"+imageId, params, args, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// createVPC works like `vpc.Client.CreateVPC`, and sends the extended fields
+// as well
+func createVPC(client *vpc.Client, args *createVPCArgs) (*vpc.CreateVPCResult, error) {
+	params := map[string]string{}
+	if args.ClientToken != "" {
+		params["clientToken"] = args.ClientToken
+	}
+
+	result := &vpc.CreateVPCResult{}
+	err := SendRequest(client, http.POST, vpc.URI_PREFIX+vpc.REQUEST_VPC_URL, params, args, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// createSubnet works like `vpc.Client.CreateSubnet`, and sends the extended
+// fields as well
+func createSubnet(client *vpc.Client, args *createSubnetArgs) (*vpc.CreateSubnetResult, error) {
+	params := map[string]string{}
+	if args.ClientToken != "" {
+		params["clientToken"] = args.ClientToken
+	}
+
+	result := &vpc.CreateSubnetResult{}
+	err := SendRequest(client, http.POST, vpc.URI_PREFIX+vpc.REQUEST_SUBNET_URL, params, args, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// listEnterpriseSecurityGroups lists a page of the enterprise security groups
+// from marker
+func listEnterpriseSecurityGroups(client *bcc.Client, marker string) (*listEnterpriseSecurityGroupsResult, error) {
+	params := map[string]string{"maxKeys": "1000"}
+	if marker != "" {
+		params["marker"] = marker
+	}
+
+	result := &listEnterpriseSecurityGroupsResult{}
+	err := SendRequest(client, http.GET, api.URI_PREFIXV2+"/enterprise/security", params, nil, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SendRequest sends args as json body to uri, and parses the json response
+// into result
+func SendRequest(client bce.Client, method, uri string, params map[string]string, args, result interface{}) error {
+	req := &bce.BceRequest{}
+	req.SetUri(uri)
+	req.SetMethod(method)
+	req.SetParams(params)
+
+	if args != nil {
+		jsonBytes, err := json.Marshal(args)
+		if err != nil {
+			return err
+		}
+		body, err := bce.NewBodyFromBytes(jsonBytes)
+		if err != nil {
+			return err
+		}
+		req.SetBody(body)
+	}
+
+	resp := &bce.BceResponse{}
+	if err := client.SendRequest(req, resp); err != nil {
+		return err
+	}
+	if resp.IsFail() {
+		return resp.ServiceError()
+	}
+	if result == nil {
+		return resp.Body().Close()
+	}
+	return resp.ParseJsonBody(result)
+}
@@ -43,6 +43,12 @@ func (a *Artifact) Id() string {
 }
 
 func (a *Artifact) String() string {
+	// no image is created if 'skip_create_image' is true, and the instance
+	// has been deleted along with the other temporary resources
+	if provisioned, _ := a.StateData["provisioned"].(bool); provisioned && len(a.BaiduCloudImages) == 0 {
+		return "No image was created, the provisioning succeeded"
+	}
+
 	parts := make([]string, 0, len(a.BaiduCloudImages))
 	for region, bccImageId := range a.BaiduCloudImages {
 		parts = append(parts, fmt.Sprintf("%s: %s", region, bccImageId))
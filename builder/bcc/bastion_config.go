@@ -0,0 +1,40 @@
+//go:generate packer-sdc struct-markdown
+
+package bcc
+
+import (
+	"errors"
+)
+
+// TemporaryBastionConfig launches a temporary bastion instance with an eip in
+// the vpc and subnet of the build instance, and connects to the build
+// instance through it by its private ip. The bastion uses the same keypair
+// or temporary password as the build instance, and is deleted once the image
+// is created.
+type TemporaryBastionConfig struct {
+	// The spec of the bastion instance, a small one is enough.
+	InstanceSpec string `mapstructure:"instance_spec" required:"true"`
+	// The image of the bastion instance. Defaults to `source_image_id`.
+	SourceImageId string `mapstructure:"source_image_id" required:"false"`
+	// The ssh user of the bastion instance. Defaults to `root`.
+	SSHUsername string `mapstructure:"ssh_username" required:"false"`
+	// The bandwidth of the eip of the bastion instance. Defaults to `1`.
+	NetworkCapacityInMbps int `mapstructure:"network_capacity_in_mbps" required:"false"`
+}
+
+func (c *TemporaryBastionConfig) Prepare(sourceImageId string) []error {
+	var errs []error
+	if c.InstanceSpec == "" {
+		errs = append(errs, errors.New("'instance_spec' of 'temporary_bastion' must be specified"))
+	}
+	if c.SourceImageId == "" {
+		c.SourceImageId = sourceImageId
+	}
+	if c.SSHUsername == "" {
+		c.SSHUsername = "root"
+	}
+	if c.NetworkCapacityInMbps < 1 {
+		c.NetworkCapacityInMbps = 1
+	}
+	return errs
+}
@@ -1,9 +1,10 @@
-//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,NetworkFilter,TemporaryBastionConfig,NetworkInterfaceConfig
 
 package bcc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/baidubce/bce-sdk-go/services/bcc/api"
@@ -15,6 +16,7 @@ import (
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/template/config"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
 )
 
 // The unique ID for this builder component
@@ -59,12 +61,46 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
 	errs = packersdk.MultiErrorAppend(errs, b.config.BaiduCloudImageConfig.Prepare(&b.config.ctx)...)
 	errs = packersdk.MultiErrorAppend(errs, b.config.BaiduCloudRunConfig.Prepare(&b.config.ctx)...)
 
+	if b.config.DedicatedHostId != "" {
+		// dedicated host belongs to a single zone, which may be derived from
+		// the subnet. Whether the host is in that zone is checked by
+		// stepConfigPlacement, since Prepare doesn't query the api
+		existingSubnet := b.config.SubnetId != "" || !b.config.SubnetFilter.Empty()
+		if b.config.Zone == "" && !existingSubnet {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("'zone' or 'subnet_id' must be specified, since the 'dedicated_host_id' has been set"))
+		} else if len(b.config.candidateZones()) > 1 {
+			errs = packersdk.MultiErrorAppend(errs, errors.New("'zones' can't be used along with 'dedicated_host_id'"))
+		}
+	}
+
+	if b.config.ImageEncryptKeyId != "" {
+		// the image isn't copied to the source region
+		for _, region := range b.config.DestinationRegions {
+			if _, ok := b.config.ImageCopyEncryptKeyIds[region]; !ok && region != b.config.BaiduCloudRegion {
+				errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("'image_copy_encrypt_key_ids' must contain a key for region(%s), "+
+					"since the 'image_encrypt_key_id' has been set", region))
+			}
+		}
+	}
+
 	if errs != nil && len(errs.Errors) > 0 {
 		return nil, nil, errs
 	}
 
-	packersdk.LogSecretFilter.Set(b.config.BaiduCloudAccessKey, b.config.BaiduCloudSecretKey)
-	return nil, nil, nil
+	packersdk.LogSecretFilter.Set(b.config.BaiduCloudAccessKey, b.config.BaiduCloudSecretKey, b.config.Comm.WinRMPassword)
+
+	// the region is used by the cloud assistant provisioner
+	generatedData := []string{"Region"}
+	if b.config.SSHTemporaryPassword {
+		packersdk.LogSecretFilter.Set(b.config.Comm.SSHPassword)
+		generatedData = append(generatedData, "TemporaryPassword")
+	}
+	var warnings []string
+	if b.config.KeypairId != "" && b.config.Comm.SSHKeyPairName != "" {
+		warnings = append(warnings, "Both 'keypair_id' and 'ssh_keypair_name' are specified, 'ssh_keypair_name' is ignored")
+	}
+
+	return generatedData, warnings, nil
 }
 
 func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook) (packersdk.Artifact, error) {
@@ -81,87 +117,216 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 	if err != nil {
 		return nil, err
 	}
+	eniClient, err := b.config.EniClient()
+	if err != nil {
+		return nil, err
+	}
+	dccClient, err := b.config.DccClient()
+	if err != nil {
+		return nil, err
+	}
+	quotaClient, err := b.config.QuotaCenterClient()
+	if err != nil {
+		return nil, err
+	}
+	billingClient, err := b.config.BillingClient()
+	if err != nil {
+		return nil, err
+	}
+
+	// the steps can cancel the build, e.g. when the spot instance is
+	// reclaimed
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	ui.Say("connect to client:" + client.Config.Endpoint)
 	state := new(multistep.BasicStateBag)
+	state.Put("cancel_build", cancel)
 	state.Put("config", &b.config)
 	state.Put("client", client)
 	state.Put("vpc_client", vpcClient)
 	state.Put("eip_client", eipClient)
+	state.Put("eni_client", eniClient)
+	state.Put("dcc_client", dccClient)
+	state.Put("quota_client", quotaClient)
+	state.Put("billing_client", billingClient)
 	state.Put("hook", hook)
 	state.Put("ui", ui)
+	state.Put("communicator_config", &b.config.Comm)
+
+	generatedData := map[string]interface{}{
+		"Region": b.config.BaiduCloudRegion,
+	}
+	if b.config.SSHTemporaryPassword {
+		generatedData["TemporaryPassword"] = b.config.Comm.SSHPassword
+	}
+	state.Put("generated_data", generatedData)
 
 	var steps []multistep.Step
 
+	configSubnet := &stepConfigSubnet{
+		UseDefaultNetwork: b.config.UseDefaultNetwork,
+		SubnetId:          b.config.SubnetId,
+		Filter:            b.config.SubnetFilter,
+		SubnetName:        b.config.SubnetName,
+		SubnetCidrBlock:   b.config.SubnetCidrBlock,
+		PrefixLength:      b.config.SubnetCidrPrefixLength,
+		AvoidCidrs:        b.config.AvoidCidrs,
+		ZoneName:          b.config.Zone,
+		Description:       "subnet for packer",
+		EnableIpv6:        b.config.EnableIpv6,
+	}
+
+	bastion := &stepTemporaryBastion{
+		Config:       b.config.TemporaryBastion,
+		Comm:         &b.config.Comm,
+		InstanceName: b.config.InstanceName,
+	}
+
+	networkInterfaces := &stepConfigNetworkInterfaces{
+		Interfaces: b.config.NetworkInterfaces,
+		Name:       fmt.Sprintf("packer_%s", uuid.TimeOrderedUUID()[:8]),
+	}
+
+	preValidate := &stepPreValidate{
+		SourceImageId:       b.config.SourceImageId,
+		CustomImageName:     b.config.ImageName,
+		SkipImageValidation: b.config.SkipImageValidation,
+	}
+
 	// Build the steps
 	steps = []multistep.Step{
-		&stepPreValidate{
-			SourceImageId:       b.config.SourceImageId,
-			CustomImageName:     b.config.ImageName,
-			SkipImageValidation: b.config.SkipImageValidation,
-		},
+		preValidate,
 		&stepConfigKeyPair{
-			Debug:        b.config.PackerDebug,
-			Comm:         &b.config.Comm,
-			KeyPairId:    b.config.KeypairId,
-			DebugKeyPath: fmt.Sprintf("bcc_%s.pem", b.config.PackerBuildName),
-			Description:  "keypair for packer",
-		},
-		&stepConfigVPC{
-			UseDefaultNetwork: b.config.UseDefaultNetwork,
-			VpcId:             b.config.VpcId,
-			VpcName:           b.config.VpcName,
-			CidrBlock:         b.config.CidrBlock,
-			Description:       "vpc for packer",
-		},
-		&stepConfigSubnet{
-			UseDefaultNetwork: b.config.UseDefaultNetwork,
-			SubnetId:          b.config.SubnetId,
-			SubnetName:        b.config.SubnetName,
-			SubnetCidrBlock:   b.config.SubnetCidrBlock,
-			ZoneName:          b.config.Zone,
-			Description:       "subnet for packer",
-		},
-		&stepConfigSecurityGroup{
-			UseDefaultNetwork: b.config.UseDefaultNetwork,
-			SecurityGroupId:   b.config.SecurityGroupId,
-			SecurityGroupName: b.config.SecurityGroupName,
-			Description:       "security group for packer",
-		},
-		&stepCreateInstance{
-			UseDefaultNetwork:        b.config.UseDefaultNetwork,
-			SourceImageId:            b.config.SourceImageId,
-			InstanceName:             b.config.InstanceName,
-			InstanceSpec:             b.config.InstanceSpec,
-			ZoneName:                 b.config.Zone,
-			AssociatePublicIpAddress: b.config.AssociatePublicIpAddress,
-			EipName:                  b.config.EipName,
-			NetworkCapacityInMbps:    b.config.NetworkCapacityInMbps,
-			InternetChargeType:       b.config.InternetChargeType,
-			UserData:                 b.config.UserData,
-			UserDataFile:             b.config.UserDataFile,
-			Tags:                     b.config.RunTags,
+			Debug:         b.config.PackerDebug,
+			Comm:          &b.config.Comm,
+			KeyPairId:     b.config.KeypairId,
+			DebugKeyPath:  fmt.Sprintf("bcc_%s.pem", b.config.PackerBuildName),
+			Description:   "keypair for packer",
+			Import:        b.config.ImportTemporaryKeyPair,
+			PublicKeyFile: b.config.SSHPublicKeyFile,
 		},
+	}
+	if b.config.ReuseInstanceId != "" {
+		// the reused instance keeps its own network
+		steps = append(steps, &stepRebuildInstance{
+			InstanceId:    b.config.ReuseInstanceId,
+			SourceImageId: b.config.SourceImageId,
+		})
+	} else if b.config.SourceInstanceId != "" {
+		// the image is captured from the existing instance, nothing is
+		// launched for it
+		steps = append(steps, &stepSourceInstance{
+			InstanceId: b.config.SourceInstanceId,
+			Start:      b.config.Comm.Type != "none",
+		})
+	} else {
+		steps = append(steps,
+			&stepConfigVPC{
+				UseDefaultNetwork: b.config.UseDefaultNetwork,
+				VpcId:             b.config.VpcId,
+				Filter:            b.config.VpcFilter,
+				VpcName:           b.config.VpcName,
+				CidrBlock:         b.config.CidrBlock,
+				Description:       "vpc for packer",
+				EnableIpv6:        b.config.EnableIpv6,
+			},
+			configSubnet,
+			&stepConfigSecurityGroup{
+				UseDefaultNetwork:          b.config.UseDefaultNetwork,
+				SecurityGroupId:            b.config.SecurityGroupId,
+				Filter:                     b.config.SecurityGroupFilter,
+				SecurityGroupIds:           b.config.SecurityGroupIds,
+				EnterpriseSecurityGroupIds: b.config.EnterpriseSecurityGroupIds,
+				SecurityGroupName:          b.config.SecurityGroupName,
+				Description:                "security group for packer",
+			},
+			&stepConfigNatGateway{
+				Enabled:         b.config.TemporaryNatGateway,
+				Name:            b.config.packerId,
+				BandwidthInMbps: b.config.TemporaryNatGatewayBandwidthInMbps,
+			},
+			&stepConfigPlacement{
+				DeploymentSetId:         b.config.DeploymentSetId,
+				AutoCreateDeploymentSet: b.config.AutoCreateDeploymentSet,
+				DedicatedHostId:         b.config.DedicatedHostId,
+				Subnet:                  configSubnet,
+				Description:             "deployment set for packer",
+			},
+			&stepCreateInstance{
+				UseDefaultNetwork:        b.config.UseDefaultNetwork,
+				SourceImageId:            b.config.SourceImageId,
+				InstanceName:             b.config.InstanceName,
+				InstanceSpecs:            b.config.candidateInstanceSpecs(),
+				ZoneNames:                b.config.candidateZones(),
+				Subnet:                   configSubnet,
+				AssociatePublicIpAddress: b.config.AssociatePublicIpAddress,
+				EipName:                  b.config.EipName,
+				NetworkCapacityInMbps:    b.config.NetworkCapacityInMbps,
+				InternetChargeType:       b.config.InternetChargeType,
+				UserData:                 b.config.UserData,
+				UserDataFile:             b.config.UserDataFile,
+				UserDataFiles:            b.config.UserDataFiles,
+				Tags:                     b.config.RunTags,
+				SpotStrategy:             b.config.SpotStrategy,
+				SpotPriceLimit:           b.config.SpotPriceLimit,
+				SpotFallbackToOnDemand:   b.config.SpotFallbackToOnDemand,
+				DedicatedHostId:          b.config.DedicatedHostId,
+			},
+			networkInterfaces,
+		)
+	}
+	steps = append(steps,
+		bastion,
 		&communicator.StepConnect{
 			Config:    &b.config.BaiduCloudRunConfig.Comm,
 			SSHConfig: b.config.BaiduCloudRunConfig.Comm.SSHConfigFunc(),
-			Host:      getSSHHost(b.config.AssociatePublicIpAddress),
+			Host:      getSSHHost(b.config.SSHInterface, b.config.AssociatePublicIpAddress),
+		},
+		&stepWaitForUserData{
+			Enabled: b.config.WaitForUserData,
+			Timeout: b.config.UserDataTimeout,
+			Windows: b.config.isWindows(),
 		},
 		&commonsteps.StepProvision{},
 		&commonsteps.StepCleanupTempKeys{
 			Comm: &b.config.Comm,
 		},
 		&stepDetachKeyPair{},
-		// &stepStopInstance{},
-		&stepCreateImage{},
+		&stepResetPassword{
+			Enabled: b.config.ResetTemporaryPassword,
+		},
+		&stepWindowsSysprep{
+			Enabled: b.config.WindowsSysprep,
+		},
+		&stepReleaseNetworkInterfaces{
+			NetworkInterfaces: networkInterfaces,
+		},
+		&stepStopInstance{
+			DisableStop: !b.config.StopSourceInstance,
+		},
+		&stepCreateImage{
+			Skip: b.config.SkipCreateImage,
+		},
+		&stepReleaseBastion{
+			Bastion: bastion,
+		},
 		&stepRemoteCopyImage{
 			DestinationRegions: b.config.DestinationRegions,
 			SourceRegion:       b.config.BaiduCloudRegion,
+			EncryptKeyIds:      b.config.ImageCopyEncryptKeyIds,
 		},
 		&stepShareImage{
 			shareAccouts:    b.config.ImageShareAccounts,
 			shareAccountIds: b.config.ImageShareAccountIds,
 		},
+	)
+
+	if b.config.DryRun {
+		steps = []multistep.Step{
+			preValidate,
+			&stepDryRun{},
+		}
 	}
 
 	b.runner = commonsteps.NewRunner(steps, b.config.PackerConfig, ui)
@@ -170,19 +335,46 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 	if rawErr, ok := state.GetOk("error"); ok {
 		return nil, rawErr.(error)
 	}
+	if b.config.DryRun {
+		return nil, nil
+	}
 
 	// build the artifact and return it
+	images := map[string]string{}
+	if rawImages, ok := state.GetOk("baiducloud_images"); ok {
+		images = rawImages.(map[string]string)
+	}
 	artifact := &Artifact{
-		BaiduCloudImages: state.Get("baiducloud_images").(map[string]string),
+		BaiduCloudImages: images,
 		BuilderIdValue:   BuilderId,
-		Client:           client,
+		StateData: map[string]interface{}{
+			"instance_spec": state.Get("instance_spec"),
+			"zone":          state.Get("zone"),
+		},
+		Client: client,
+	}
+	if b.config.SkipCreateImage {
+		// the provisioners succeeded, otherwise the build has failed. The
+		// instance isn't recorded, since it has been deleted
+		artifact.StateData["provisioned"] = true
 	}
 	return artifact, nil
 }
 
-func getSSHHost(publicIp bool) func(multistep.StateBag) (string, error) {
+func getSSHHost(sshInterface string, publicIp bool) func(multistep.StateBag) (string, error) {
 	return func(state multistep.StateBag) (string, error) {
 		instance := state.Get("instance").(*api.InstanceModel)
+		switch sshInterface {
+		case sshInterfaceIpv6:
+			if instance.Ipv6 == "" {
+				return "", fmt.Errorf("The instance(%s) has no ipv6 address", instance.InstanceId)
+			}
+			return instance.Ipv6, nil
+		case sshInterfacePublicIp:
+			return instance.PublicIP, nil
+		case sshInterfacePrivateIp:
+			return instance.InternalIP, nil
+		}
 		if publicIp {
 			return instance.PublicIP, nil
 		} else {
@@ -10,96 +10,140 @@ import (
 // FlatConfig is an auto-generated flat version of Config.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatConfig struct {
-	PackerBuildName           *string           `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
-	PackerBuilderType         *string           `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
-	PackerCoreVersion         *string           `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
-	PackerDebug               *bool             `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
-	PackerForce               *bool             `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
-	PackerOnError             *string           `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
-	PackerUserVars            map[string]string `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
-	PackerSensitiveVars       []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
-	BaiduCloudAccessKey       *string           `mapstructure:"access_key" required:"true" cty:"access_key" hcl:"access_key"`
-	BaiduCloudSecretKey       *string           `mapstructure:"secret_key" required:"true" cty:"secret_key" hcl:"secret_key"`
-	BaiduCloudRegion          *string           `mapstructure:"region" required:"true" cty:"region" hcl:"region"`
-	Zone                      *string           `mapstructure:"zone" required:"true" cty:"zone" hcl:"zone"`
-	SkipValidation            *bool             `mapstructure:"skip_region_validation" required:"false" cty:"skip_region_validation" hcl:"skip_region_validation"`
-	ImageName                 *string           `mapstructure:"image_name" required:"true" cty:"image_name" hcl:"image_name"`
-	DestinationRegions        []string          `mapstructure:"image_copy_regions" required:"false" cty:"image_copy_regions" hcl:"image_copy_regions"`
-	ImageShareAccounts        []string          `mapstructure:"image_share_accounts" required:"false" cty:"image_share_accounts" hcl:"image_share_accounts"`
-	ImageShareAccountIds      []string          `mapstructure:"image_share_account_ids" required:"false" cty:"image_share_account_ids" hcl:"image_share_account_ids"`
-	SkipImageValidation       *bool             `mapstructure:"skip_image_validation" required:"false" cty:"skip_image_validation" hcl:"skip_image_validation"`
-	AssociatePublicIpAddress  *bool             `mapstructure:"associate_public_ip_address" required:"false" cty:"associate_public_ip_address" hcl:"associate_public_ip_address"`
-	UseDefaultNetwork         *bool             `mapstructure:"use_default_network" required:"false" cty:"use_default_network" hcl:"use_default_network"`
-	InstanceSpec              *string           `mapstructure:"instance_spec" required:"true" cty:"instance_spec" hcl:"instance_spec"`
-	InstanceName              *string           `mapstructure:"instance_name" required:"false" cty:"instance_name" hcl:"instance_name"`
-	Description               *string           `mapstructure:"description" cty:"description" hcl:"description"`
-	SourceImageId             *string           `mapstructure:"source_image_id" required:"true" cty:"source_image_id" hcl:"source_image_id"`
-	SecurityGroupId           *string           `mapstructure:"security_group_id" required:"false" cty:"security_group_id" hcl:"security_group_id"`
-	SecurityGroupName         *string           `mapstructure:"security_group_name" required:"false" cty:"security_group_name" hcl:"security_group_name"`
-	InternetChargeType        *string           `mapstructure:"internet_charge_type" required:"false" cty:"internet_charge_type" hcl:"internet_charge_type"`
-	EipName                   *string           `mapstructure:"eip_name" required:"false" cty:"eip_name" hcl:"eip_name"`
-	NetworkCapacityInMbps     *int              `mapstructure:"network_capacity_in_mbps" required:"false" cty:"network_capacity_in_mbps" hcl:"network_capacity_in_mbps"`
-	RootDiskSizeInGb          *int              `mapstructure:"root_disk_size_in_gb" required:"false" cty:"root_disk_size_in_gb" hcl:"root_disk_size_in_gb"`
-	RootDiskStorageType       *string           `mapstructure:"root_disk_storage_type" required:"false" cty:"root_disk_storage_type" hcl:"root_disk_storage_type"`
-	VpcId                     *string           `mapstructure:"vpc_id" require:"false" cty:"vpc_id" hcl:"vpc_id"`
-	VpcName                   *string           `mapstructure:"vpc_name" require:"false" cty:"vpc_name" hcl:"vpc_name"`
-	CidrBlock                 *string           `mapstructure:"vpc_cidr_block" required:"false" cty:"vpc_cidr_block" hcl:"vpc_cidr_block"`
-	SubnetId                  *string           `mapstructure:"subnet_id" required:"false" cty:"subnet_id" hcl:"subnet_id"`
-	SubnetCidrBlock           *string           `mapstructure:"subnet_cidr_block" required:"false" cty:"subnet_cidr_block" hcl:"subnet_cidr_block"`
-	SubnetName                *string           `mapstructure:"subnet_name" required:"false" cty:"subnet_name" hcl:"subnet_name"`
-	KeypairId                 *string           `mapstructure:"keypair_id" required:"false" cty:"keypair_id" hcl:"keypair_id"`
-	RunTags                   map[string]string `mapstructure:"run_tags" required:"false" cty:"run_tags" hcl:"run_tags"`
-	UserData                  *string           `mapstructure:"user_data" required:"false" cty:"user_data" hcl:"user_data"`
-	UserDataFile              *string           `mapstructure:"user_data_file" required:"false" cty:"user_data_file" hcl:"user_data_file"`
-	Type                      *string           `mapstructure:"communicator" cty:"communicator" hcl:"communicator"`
-	PauseBeforeConnect        *string           `mapstructure:"pause_before_connecting" cty:"pause_before_connecting" hcl:"pause_before_connecting"`
-	SSHHost                   *string           `mapstructure:"ssh_host" cty:"ssh_host" hcl:"ssh_host"`
-	SSHPort                   *int              `mapstructure:"ssh_port" cty:"ssh_port" hcl:"ssh_port"`
-	SSHUsername               *string           `mapstructure:"ssh_username" cty:"ssh_username" hcl:"ssh_username"`
-	SSHPassword               *string           `mapstructure:"ssh_password" cty:"ssh_password" hcl:"ssh_password"`
-	SSHKeyPairName            *string           `mapstructure:"ssh_keypair_name" undocumented:"true" cty:"ssh_keypair_name" hcl:"ssh_keypair_name"`
-	SSHTemporaryKeyPairName   *string           `mapstructure:"temporary_key_pair_name" undocumented:"true" cty:"temporary_key_pair_name" hcl:"temporary_key_pair_name"`
-	SSHTemporaryKeyPairType   *string           `mapstructure:"temporary_key_pair_type" cty:"temporary_key_pair_type" hcl:"temporary_key_pair_type"`
-	SSHTemporaryKeyPairBits   *int              `mapstructure:"temporary_key_pair_bits" cty:"temporary_key_pair_bits" hcl:"temporary_key_pair_bits"`
-	SSHCiphers                []string          `mapstructure:"ssh_ciphers" cty:"ssh_ciphers" hcl:"ssh_ciphers"`
-	SSHClearAuthorizedKeys    *bool             `mapstructure:"ssh_clear_authorized_keys" cty:"ssh_clear_authorized_keys" hcl:"ssh_clear_authorized_keys"`
-	SSHKEXAlgos               []string          `mapstructure:"ssh_key_exchange_algorithms" cty:"ssh_key_exchange_algorithms" hcl:"ssh_key_exchange_algorithms"`
-	SSHPrivateKeyFile         *string           `mapstructure:"ssh_private_key_file" undocumented:"true" cty:"ssh_private_key_file" hcl:"ssh_private_key_file"`
-	SSHCertificateFile        *string           `mapstructure:"ssh_certificate_file" cty:"ssh_certificate_file" hcl:"ssh_certificate_file"`
-	SSHPty                    *bool             `mapstructure:"ssh_pty" cty:"ssh_pty" hcl:"ssh_pty"`
-	SSHTimeout                *string           `mapstructure:"ssh_timeout" cty:"ssh_timeout" hcl:"ssh_timeout"`
-	SSHWaitTimeout            *string           `mapstructure:"ssh_wait_timeout" undocumented:"true" cty:"ssh_wait_timeout" hcl:"ssh_wait_timeout"`
-	SSHAgentAuth              *bool             `mapstructure:"ssh_agent_auth" undocumented:"true" cty:"ssh_agent_auth" hcl:"ssh_agent_auth"`
-	SSHDisableAgentForwarding *bool             `mapstructure:"ssh_disable_agent_forwarding" cty:"ssh_disable_agent_forwarding" hcl:"ssh_disable_agent_forwarding"`
-	SSHHandshakeAttempts      *int              `mapstructure:"ssh_handshake_attempts" cty:"ssh_handshake_attempts" hcl:"ssh_handshake_attempts"`
-	SSHBastionHost            *string           `mapstructure:"ssh_bastion_host" cty:"ssh_bastion_host" hcl:"ssh_bastion_host"`
-	SSHBastionPort            *int              `mapstructure:"ssh_bastion_port" cty:"ssh_bastion_port" hcl:"ssh_bastion_port"`
-	SSHBastionAgentAuth       *bool             `mapstructure:"ssh_bastion_agent_auth" cty:"ssh_bastion_agent_auth" hcl:"ssh_bastion_agent_auth"`
-	SSHBastionUsername        *string           `mapstructure:"ssh_bastion_username" cty:"ssh_bastion_username" hcl:"ssh_bastion_username"`
-	SSHBastionPassword        *string           `mapstructure:"ssh_bastion_password" cty:"ssh_bastion_password" hcl:"ssh_bastion_password"`
-	SSHBastionInteractive     *bool             `mapstructure:"ssh_bastion_interactive" cty:"ssh_bastion_interactive" hcl:"ssh_bastion_interactive"`
-	SSHBastionPrivateKeyFile  *string           `mapstructure:"ssh_bastion_private_key_file" cty:"ssh_bastion_private_key_file" hcl:"ssh_bastion_private_key_file"`
-	SSHBastionCertificateFile *string           `mapstructure:"ssh_bastion_certificate_file" cty:"ssh_bastion_certificate_file" hcl:"ssh_bastion_certificate_file"`
-	SSHFileTransferMethod     *string           `mapstructure:"ssh_file_transfer_method" cty:"ssh_file_transfer_method" hcl:"ssh_file_transfer_method"`
-	SSHProxyHost              *string           `mapstructure:"ssh_proxy_host" cty:"ssh_proxy_host" hcl:"ssh_proxy_host"`
-	SSHProxyPort              *int              `mapstructure:"ssh_proxy_port" cty:"ssh_proxy_port" hcl:"ssh_proxy_port"`
-	SSHProxyUsername          *string           `mapstructure:"ssh_proxy_username" cty:"ssh_proxy_username" hcl:"ssh_proxy_username"`
-	SSHProxyPassword          *string           `mapstructure:"ssh_proxy_password" cty:"ssh_proxy_password" hcl:"ssh_proxy_password"`
-	SSHKeepAliveInterval      *string           `mapstructure:"ssh_keep_alive_interval" cty:"ssh_keep_alive_interval" hcl:"ssh_keep_alive_interval"`
-	SSHReadWriteTimeout       *string           `mapstructure:"ssh_read_write_timeout" cty:"ssh_read_write_timeout" hcl:"ssh_read_write_timeout"`
-	SSHRemoteTunnels          []string          `mapstructure:"ssh_remote_tunnels" cty:"ssh_remote_tunnels" hcl:"ssh_remote_tunnels"`
-	SSHLocalTunnels           []string          `mapstructure:"ssh_local_tunnels" cty:"ssh_local_tunnels" hcl:"ssh_local_tunnels"`
-	SSHPublicKey              []byte            `mapstructure:"ssh_public_key" undocumented:"true" cty:"ssh_public_key" hcl:"ssh_public_key"`
-	SSHPrivateKey             []byte            `mapstructure:"ssh_private_key" undocumented:"true" cty:"ssh_private_key" hcl:"ssh_private_key"`
-	WinRMUser                 *string           `mapstructure:"winrm_username" cty:"winrm_username" hcl:"winrm_username"`
-	WinRMPassword             *string           `mapstructure:"winrm_password" cty:"winrm_password" hcl:"winrm_password"`
-	WinRMHost                 *string           `mapstructure:"winrm_host" cty:"winrm_host" hcl:"winrm_host"`
-	WinRMNoProxy              *bool             `mapstructure:"winrm_no_proxy" cty:"winrm_no_proxy" hcl:"winrm_no_proxy"`
-	WinRMPort                 *int              `mapstructure:"winrm_port" cty:"winrm_port" hcl:"winrm_port"`
-	WinRMTimeout              *string           `mapstructure:"winrm_timeout" cty:"winrm_timeout" hcl:"winrm_timeout"`
-	WinRMUseSSL               *bool             `mapstructure:"winrm_use_ssl" cty:"winrm_use_ssl" hcl:"winrm_use_ssl"`
-	WinRMInsecure             *bool             `mapstructure:"winrm_insecure" cty:"winrm_insecure" hcl:"winrm_insecure"`
-	WinRMUseNTLM              *bool             `mapstructure:"winrm_use_ntlm" cty:"winrm_use_ntlm" hcl:"winrm_use_ntlm"`
+	PackerBuildName                    *string                      `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
+	PackerBuilderType                  *string                      `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
+	PackerCoreVersion                  *string                      `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
+	PackerDebug                        *bool                        `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
+	PackerForce                        *bool                        `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
+	PackerOnError                      *string                      `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
+	PackerUserVars                     map[string]string            `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
+	PackerSensitiveVars                []string                     `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
+	BaiduCloudAccessKey                *string                      `mapstructure:"access_key" required:"true" cty:"access_key" hcl:"access_key"`
+	BaiduCloudSecretKey                *string                      `mapstructure:"secret_key" required:"true" cty:"secret_key" hcl:"secret_key"`
+	BaiduCloudRegion                   *string                      `mapstructure:"region" required:"true" cty:"region" hcl:"region"`
+	Zone                               *string                      `mapstructure:"zone" required:"true" cty:"zone" hcl:"zone"`
+	Zones                              []string                     `mapstructure:"zones" required:"false" cty:"zones" hcl:"zones"`
+	SkipValidation                     *bool                        `mapstructure:"skip_region_validation" required:"false" cty:"skip_region_validation" hcl:"skip_region_validation"`
+	ImageName                          *string                      `mapstructure:"image_name" required:"true" cty:"image_name" hcl:"image_name"`
+	SkipCreateImage                    *bool                        `mapstructure:"skip_create_image" required:"false" cty:"skip_create_image" hcl:"skip_create_image"`
+	DestinationRegions                 []string                     `mapstructure:"image_copy_regions" required:"false" cty:"image_copy_regions" hcl:"image_copy_regions"`
+	ImageEncryptKeyId                  *string                      `mapstructure:"image_encrypt_key_id" required:"false" cty:"image_encrypt_key_id" hcl:"image_encrypt_key_id"`
+	ImageCopyEncryptKeyIds             map[string]string            `mapstructure:"image_copy_encrypt_key_ids" required:"false" cty:"image_copy_encrypt_key_ids" hcl:"image_copy_encrypt_key_ids"`
+	ImageShareAccounts                 []string                     `mapstructure:"image_share_accounts" required:"false" cty:"image_share_accounts" hcl:"image_share_accounts"`
+	ImageShareAccountIds               []string                     `mapstructure:"image_share_account_ids" required:"false" cty:"image_share_account_ids" hcl:"image_share_account_ids"`
+	SkipImageValidation                *bool                        `mapstructure:"skip_image_validation" required:"false" cty:"skip_image_validation" hcl:"skip_image_validation"`
+	AssociatePublicIpAddress           *bool                        `mapstructure:"associate_public_ip_address" required:"false" cty:"associate_public_ip_address" hcl:"associate_public_ip_address"`
+	TemporaryNatGateway                *bool                        `mapstructure:"temporary_nat_gateway" required:"false" cty:"temporary_nat_gateway" hcl:"temporary_nat_gateway"`
+	TemporaryNatGatewayBandwidthInMbps *int                         `mapstructure:"temporary_nat_gateway_bandwidth_in_mbps" required:"false" cty:"temporary_nat_gateway_bandwidth_in_mbps" hcl:"temporary_nat_gateway_bandwidth_in_mbps"`
+	TemporaryBastion                   *FlatTemporaryBastionConfig  `mapstructure:"temporary_bastion" required:"false" cty:"temporary_bastion" hcl:"temporary_bastion"`
+	UseDefaultNetwork                  *bool                        `mapstructure:"use_default_network" required:"false" cty:"use_default_network" hcl:"use_default_network"`
+	ReuseInstanceId                    *string                      `mapstructure:"reuse_instance_id" required:"false" cty:"reuse_instance_id" hcl:"reuse_instance_id"`
+	SourceInstanceId                   *string                      `mapstructure:"source_instance_id" required:"false" cty:"source_instance_id" hcl:"source_instance_id"`
+	StopSourceInstance                 *bool                        `mapstructure:"stop_source_instance" required:"false" cty:"stop_source_instance" hcl:"stop_source_instance"`
+	InstanceSpec                       *string                      `mapstructure:"instance_spec" required:"true" cty:"instance_spec" hcl:"instance_spec"`
+	InstanceSpecs                      []string                     `mapstructure:"instance_specs" required:"false" cty:"instance_specs" hcl:"instance_specs"`
+	InstanceName                       *string                      `mapstructure:"instance_name" required:"false" cty:"instance_name" hcl:"instance_name"`
+	Description                        *string                      `mapstructure:"description" cty:"description" hcl:"description"`
+	SourceImageId                      *string                      `mapstructure:"source_image_id" required:"true" cty:"source_image_id" hcl:"source_image_id"`
+	SecurityGroupId                    *string                      `mapstructure:"security_group_id" required:"false" cty:"security_group_id" hcl:"security_group_id"`
+	SecurityGroupName                  *string                      `mapstructure:"security_group_name" required:"false" cty:"security_group_name" hcl:"security_group_name"`
+	SecurityGroupFilter                *FlatNetworkFilter           `mapstructure:"security_group_filter" required:"false" cty:"security_group_filter" hcl:"security_group_filter"`
+	SecurityGroupIds                   []string                     `mapstructure:"security_group_ids" required:"false" cty:"security_group_ids" hcl:"security_group_ids"`
+	EnterpriseSecurityGroupIds         []string                     `mapstructure:"enterprise_security_group_ids" required:"false" cty:"enterprise_security_group_ids" hcl:"enterprise_security_group_ids"`
+	NetworkInterfaces                  []FlatNetworkInterfaceConfig `mapstructure:"network_interfaces" required:"false" cty:"network_interfaces" hcl:"network_interfaces"`
+	InternetChargeType                 *string                      `mapstructure:"internet_charge_type" required:"false" cty:"internet_charge_type" hcl:"internet_charge_type"`
+	EipName                            *string                      `mapstructure:"eip_name" required:"false" cty:"eip_name" hcl:"eip_name"`
+	NetworkCapacityInMbps              *int                         `mapstructure:"network_capacity_in_mbps" required:"false" cty:"network_capacity_in_mbps" hcl:"network_capacity_in_mbps"`
+	SpotStrategy                       *string                      `mapstructure:"spot_strategy" required:"false" cty:"spot_strategy" hcl:"spot_strategy"`
+	SpotPriceLimit                     *float64                     `mapstructure:"spot_price_limit" required:"false" cty:"spot_price_limit" hcl:"spot_price_limit"`
+	SpotFallbackToOnDemand             *bool                        `mapstructure:"spot_fallback_to_on_demand" required:"false" cty:"spot_fallback_to_on_demand" hcl:"spot_fallback_to_on_demand"`
+	DeploymentSetId                    *string                      `mapstructure:"deployment_set_id" required:"false" cty:"deployment_set_id" hcl:"deployment_set_id"`
+	AutoCreateDeploymentSet            *bool                        `mapstructure:"auto_create_deployment_set" required:"false" cty:"auto_create_deployment_set" hcl:"auto_create_deployment_set"`
+	DedicatedHostId                    *string                      `mapstructure:"dedicated_host_id" required:"false" cty:"dedicated_host_id" hcl:"dedicated_host_id"`
+	RootDiskSizeInGb                   *int                         `mapstructure:"root_disk_size_in_gb" required:"false" cty:"root_disk_size_in_gb" hcl:"root_disk_size_in_gb"`
+	RootDiskStorageType                *string                      `mapstructure:"root_disk_storage_type" required:"false" cty:"root_disk_storage_type" hcl:"root_disk_storage_type"`
+	RootDiskEncryptKeyId               *string                      `mapstructure:"root_disk_encrypt_key_id" required:"false" cty:"root_disk_encrypt_key_id" hcl:"root_disk_encrypt_key_id"`
+	VpcId                              *string                      `mapstructure:"vpc_id" require:"false" cty:"vpc_id" hcl:"vpc_id"`
+	VpcName                            *string                      `mapstructure:"vpc_name" require:"false" cty:"vpc_name" hcl:"vpc_name"`
+	VpcFilter                          *FlatNetworkFilter           `mapstructure:"vpc_filter" required:"false" cty:"vpc_filter" hcl:"vpc_filter"`
+	CidrBlock                          *string                      `mapstructure:"vpc_cidr_block" required:"false" cty:"vpc_cidr_block" hcl:"vpc_cidr_block"`
+	EnableIpv6                         *bool                        `mapstructure:"enable_ipv6" required:"false" cty:"enable_ipv6" hcl:"enable_ipv6"`
+	SSHInterface                       *string                      `mapstructure:"ssh_interface" required:"false" cty:"ssh_interface" hcl:"ssh_interface"`
+	AvoidCidrs                         []string                     `mapstructure:"avoid_cidrs" required:"false" cty:"avoid_cidrs" hcl:"avoid_cidrs"`
+	SubnetId                           *string                      `mapstructure:"subnet_id" required:"false" cty:"subnet_id" hcl:"subnet_id"`
+	SubnetFilter                       *FlatNetworkFilter           `mapstructure:"subnet_filter" required:"false" cty:"subnet_filter" hcl:"subnet_filter"`
+	SubnetCidrBlock                    *string                      `mapstructure:"subnet_cidr_block" required:"false" cty:"subnet_cidr_block" hcl:"subnet_cidr_block"`
+	SubnetCidrPrefixLength             *int                         `mapstructure:"subnet_cidr_prefix_length" required:"false" cty:"subnet_cidr_prefix_length" hcl:"subnet_cidr_prefix_length"`
+	SubnetName                         *string                      `mapstructure:"subnet_name" required:"false" cty:"subnet_name" hcl:"subnet_name"`
+	KeypairId                          *string                      `mapstructure:"keypair_id" required:"false" cty:"keypair_id" hcl:"keypair_id"`
+	ImportTemporaryKeyPair             *bool                        `mapstructure:"import_temporary_key_pair" required:"false" cty:"import_temporary_key_pair" hcl:"import_temporary_key_pair"`
+	SSHPublicKeyFile                   *string                      `mapstructure:"ssh_public_key_file" required:"false" cty:"ssh_public_key_file" hcl:"ssh_public_key_file"`
+	RunTags                            map[string]string            `mapstructure:"run_tags" required:"false" cty:"run_tags" hcl:"run_tags"`
+	UserData                           *string                      `mapstructure:"user_data" required:"false" cty:"user_data" hcl:"user_data"`
+	UserDataFile                       *string                      `mapstructure:"user_data_file" required:"false" cty:"user_data_file" hcl:"user_data_file"`
+	UserDataFiles                      []string                     `mapstructure:"user_data_files" required:"false" cty:"user_data_files" hcl:"user_data_files"`
+	WaitForUserData                    *bool                        `mapstructure:"wait_for_user_data" required:"false" cty:"wait_for_user_data" hcl:"wait_for_user_data"`
+	UserDataTimeout                    *string                      `mapstructure:"user_data_timeout" required:"false" cty:"user_data_timeout" hcl:"user_data_timeout"`
+	SSHTemporaryPassword               *bool                        `mapstructure:"ssh_temporary_password" required:"false" cty:"ssh_temporary_password" hcl:"ssh_temporary_password"`
+	ResetTemporaryPassword             *bool                        `mapstructure:"reset_temporary_password" required:"false" cty:"reset_temporary_password" hcl:"reset_temporary_password"`
+	WindowsSysprep                     *bool                        `mapstructure:"windows_sysprep" required:"false" cty:"windows_sysprep" hcl:"windows_sysprep"`
+	SkipInstanceQuotaCheck             *bool                        `mapstructure:"skip_instance_quota_check" required:"false" cty:"skip_instance_quota_check" hcl:"skip_instance_quota_check"`
+	SkipVpcQuotaCheck                  *bool                        `mapstructure:"skip_vpc_quota_check" required:"false" cty:"skip_vpc_quota_check" hcl:"skip_vpc_quota_check"`
+	SkipSubnetQuotaCheck               *bool                        `mapstructure:"skip_subnet_quota_check" required:"false" cty:"skip_subnet_quota_check" hcl:"skip_subnet_quota_check"`
+	SkipSecurityGroupQuotaCheck        *bool                        `mapstructure:"skip_security_group_quota_check" required:"false" cty:"skip_security_group_quota_check" hcl:"skip_security_group_quota_check"`
+	SkipEipQuotaCheck                  *bool                        `mapstructure:"skip_eip_quota_check" required:"false" cty:"skip_eip_quota_check" hcl:"skip_eip_quota_check"`
+	SkipImageQuotaCheck                *bool                        `mapstructure:"skip_image_quota_check" required:"false" cty:"skip_image_quota_check" hcl:"skip_image_quota_check"`
+	CheckBalance                       *bool                        `mapstructure:"check_balance" required:"false" cty:"check_balance" hcl:"check_balance"`
+	DryRun                             *bool                        `mapstructure:"dry_run" required:"false" cty:"dry_run" hcl:"dry_run"`
+	Type                               *string                      `mapstructure:"communicator" cty:"communicator" hcl:"communicator"`
+	PauseBeforeConnect                 *string                      `mapstructure:"pause_before_connecting" cty:"pause_before_connecting" hcl:"pause_before_connecting"`
+	SSHHost                            *string                      `mapstructure:"ssh_host" cty:"ssh_host" hcl:"ssh_host"`
+	SSHPort                            *int                         `mapstructure:"ssh_port" cty:"ssh_port" hcl:"ssh_port"`
+	SSHUsername                        *string                      `mapstructure:"ssh_username" cty:"ssh_username" hcl:"ssh_username"`
+	SSHPassword                        *string                      `mapstructure:"ssh_password" cty:"ssh_password" hcl:"ssh_password"`
+	SSHKeyPairName                     *string                      `mapstructure:"ssh_keypair_name" undocumented:"true" cty:"ssh_keypair_name" hcl:"ssh_keypair_name"`
+	SSHTemporaryKeyPairName            *string                      `mapstructure:"temporary_key_pair_name" undocumented:"true" cty:"temporary_key_pair_name" hcl:"temporary_key_pair_name"`
+	SSHTemporaryKeyPairType            *string                      `mapstructure:"temporary_key_pair_type" cty:"temporary_key_pair_type" hcl:"temporary_key_pair_type"`
+	SSHTemporaryKeyPairBits            *int                         `mapstructure:"temporary_key_pair_bits" cty:"temporary_key_pair_bits" hcl:"temporary_key_pair_bits"`
+	SSHCiphers                         []string                     `mapstructure:"ssh_ciphers" cty:"ssh_ciphers" hcl:"ssh_ciphers"`
+	SSHClearAuthorizedKeys             *bool                        `mapstructure:"ssh_clear_authorized_keys" cty:"ssh_clear_authorized_keys" hcl:"ssh_clear_authorized_keys"`
+	SSHKEXAlgos                        []string                     `mapstructure:"ssh_key_exchange_algorithms" cty:"ssh_key_exchange_algorithms" hcl:"ssh_key_exchange_algorithms"`
+	SSHPrivateKeyFile                  *string                      `mapstructure:"ssh_private_key_file" undocumented:"true" cty:"ssh_private_key_file" hcl:"ssh_private_key_file"`
+	SSHCertificateFile                 *string                      `mapstructure:"ssh_certificate_file" cty:"ssh_certificate_file" hcl:"ssh_certificate_file"`
+	SSHPty                             *bool                        `mapstructure:"ssh_pty" cty:"ssh_pty" hcl:"ssh_pty"`
+	SSHTimeout                         *string                      `mapstructure:"ssh_timeout" cty:"ssh_timeout" hcl:"ssh_timeout"`
+	SSHWaitTimeout                     *string                      `mapstructure:"ssh_wait_timeout" undocumented:"true" cty:"ssh_wait_timeout" hcl:"ssh_wait_timeout"`
+	SSHAgentAuth                       *bool                        `mapstructure:"ssh_agent_auth" undocumented:"true" cty:"ssh_agent_auth" hcl:"ssh_agent_auth"`
+	SSHDisableAgentForwarding          *bool                        `mapstructure:"ssh_disable_agent_forwarding" cty:"ssh_disable_agent_forwarding" hcl:"ssh_disable_agent_forwarding"`
+	SSHHandshakeAttempts               *int                         `mapstructure:"ssh_handshake_attempts" cty:"ssh_handshake_attempts" hcl:"ssh_handshake_attempts"`
+	SSHBastionHost                     *string                      `mapstructure:"ssh_bastion_host" cty:"ssh_bastion_host" hcl:"ssh_bastion_host"`
+	SSHBastionPort                     *int                         `mapstructure:"ssh_bastion_port" cty:"ssh_bastion_port" hcl:"ssh_bastion_port"`
+	SSHBastionAgentAuth                *bool                        `mapstructure:"ssh_bastion_agent_auth" cty:"ssh_bastion_agent_auth" hcl:"ssh_bastion_agent_auth"`
+	SSHBastionUsername                 *string                      `mapstructure:"ssh_bastion_username" cty:"ssh_bastion_username" hcl:"ssh_bastion_username"`
+	SSHBastionPassword                 *string                      `mapstructure:"ssh_bastion_password" cty:"ssh_bastion_password" hcl:"ssh_bastion_password"`
+	SSHBastionInteractive              *bool                        `mapstructure:"ssh_bastion_interactive" cty:"ssh_bastion_interactive" hcl:"ssh_bastion_interactive"`
+	SSHBastionPrivateKeyFile           *string                      `mapstructure:"ssh_bastion_private_key_file" cty:"ssh_bastion_private_key_file" hcl:"ssh_bastion_private_key_file"`
+	SSHBastionCertificateFile          *string                      `mapstructure:"ssh_bastion_certificate_file" cty:"ssh_bastion_certificate_file" hcl:"ssh_bastion_certificate_file"`
+	SSHFileTransferMethod              *string                      `mapstructure:"ssh_file_transfer_method" cty:"ssh_file_transfer_method" hcl:"ssh_file_transfer_method"`
+	SSHProxyHost                       *string                      `mapstructure:"ssh_proxy_host" cty:"ssh_proxy_host" hcl:"ssh_proxy_host"`
+	SSHProxyPort                       *int                         `mapstructure:"ssh_proxy_port" cty:"ssh_proxy_port" hcl:"ssh_proxy_port"`
+	SSHProxyUsername                   *string                      `mapstructure:"ssh_proxy_username" cty:"ssh_proxy_username" hcl:"ssh_proxy_username"`
+	SSHProxyPassword                   *string                      `mapstructure:"ssh_proxy_password" cty:"ssh_proxy_password" hcl:"ssh_proxy_password"`
+	SSHKeepAliveInterval               *string                      `mapstructure:"ssh_keep_alive_interval" cty:"ssh_keep_alive_interval" hcl:"ssh_keep_alive_interval"`
+	SSHReadWriteTimeout                *string                      `mapstructure:"ssh_read_write_timeout" cty:"ssh_read_write_timeout" hcl:"ssh_read_write_timeout"`
+	SSHRemoteTunnels                   []string                     `mapstructure:"ssh_remote_tunnels" cty:"ssh_remote_tunnels" hcl:"ssh_remote_tunnels"`
+	SSHLocalTunnels                    []string                     `mapstructure:"ssh_local_tunnels" cty:"ssh_local_tunnels" hcl:"ssh_local_tunnels"`
+	SSHPublicKey                       []byte                       `mapstructure:"ssh_public_key" undocumented:"true" cty:"ssh_public_key" hcl:"ssh_public_key"`
+	SSHPrivateKey                      []byte                       `mapstructure:"ssh_private_key" undocumented:"true" cty:"ssh_private_key" hcl:"ssh_private_key"`
+	WinRMUser                          *string                      `mapstructure:"winrm_username" cty:"winrm_username" hcl:"winrm_username"`
+	WinRMPassword                      *string                      `mapstructure:"winrm_password" cty:"winrm_password" hcl:"winrm_password"`
+	WinRMHost                          *string                      `mapstructure:"winrm_host" cty:"winrm_host" hcl:"winrm_host"`
+	WinRMNoProxy                       *bool                        `mapstructure:"winrm_no_proxy" cty:"winrm_no_proxy" hcl:"winrm_no_proxy"`
+	WinRMPort                          *int                         `mapstructure:"winrm_port" cty:"winrm_port" hcl:"winrm_port"`
+	WinRMTimeout                       *string                      `mapstructure:"winrm_timeout" cty:"winrm_timeout" hcl:"winrm_timeout"`
+	WinRMUseSSL                        *bool                        `mapstructure:"winrm_use_ssl" cty:"winrm_use_ssl" hcl:"winrm_use_ssl"`
+	WinRMInsecure                      *bool                        `mapstructure:"winrm_insecure" cty:"winrm_insecure" hcl:"winrm_insecure"`
+	WinRMUseNTLM                       *bool                        `mapstructure:"winrm_use_ntlm" cty:"winrm_use_ntlm" hcl:"winrm_use_ntlm"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -114,96 +158,227 @@ func (*Config) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec }
 // The decoded values from this spec will then be applied to a FlatConfig.
 func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
-		"packer_build_name":            &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
-		"packer_builder_type":          &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
-		"packer_core_version":          &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
-		"packer_debug":                 &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
-		"packer_force":                 &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
-		"packer_on_error":              &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
-		"packer_user_variables":        &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
-		"packer_sensitive_variables":   &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
-		"access_key":                   &hcldec.AttrSpec{Name: "access_key", Type: cty.String, Required: false},
-		"secret_key":                   &hcldec.AttrSpec{Name: "secret_key", Type: cty.String, Required: false},
-		"region":                       &hcldec.AttrSpec{Name: "region", Type: cty.String, Required: false},
-		"zone":                         &hcldec.AttrSpec{Name: "zone", Type: cty.String, Required: false},
-		"skip_region_validation":       &hcldec.AttrSpec{Name: "skip_region_validation", Type: cty.Bool, Required: false},
-		"image_name":                   &hcldec.AttrSpec{Name: "image_name", Type: cty.String, Required: false},
-		"image_copy_regions":           &hcldec.AttrSpec{Name: "image_copy_regions", Type: cty.List(cty.String), Required: false},
-		"image_share_accounts":         &hcldec.AttrSpec{Name: "image_share_accounts", Type: cty.List(cty.String), Required: false},
-		"image_share_account_ids":      &hcldec.AttrSpec{Name: "image_share_account_ids", Type: cty.List(cty.String), Required: false},
-		"skip_image_validation":        &hcldec.AttrSpec{Name: "skip_image_validation", Type: cty.Bool, Required: false},
-		"associate_public_ip_address":  &hcldec.AttrSpec{Name: "associate_public_ip_address", Type: cty.Bool, Required: false},
-		"use_default_network":          &hcldec.AttrSpec{Name: "use_default_network", Type: cty.Bool, Required: false},
-		"instance_spec":                &hcldec.AttrSpec{Name: "instance_spec", Type: cty.String, Required: false},
-		"instance_name":                &hcldec.AttrSpec{Name: "instance_name", Type: cty.String, Required: false},
-		"description":                  &hcldec.AttrSpec{Name: "description", Type: cty.String, Required: false},
-		"source_image_id":              &hcldec.AttrSpec{Name: "source_image_id", Type: cty.String, Required: false},
-		"security_group_id":            &hcldec.AttrSpec{Name: "security_group_id", Type: cty.String, Required: false},
-		"security_group_name":          &hcldec.AttrSpec{Name: "security_group_name", Type: cty.String, Required: false},
-		"internet_charge_type":         &hcldec.AttrSpec{Name: "internet_charge_type", Type: cty.String, Required: false},
-		"eip_name":                     &hcldec.AttrSpec{Name: "eip_name", Type: cty.String, Required: false},
-		"network_capacity_in_mbps":     &hcldec.AttrSpec{Name: "network_capacity_in_mbps", Type: cty.Number, Required: false},
-		"root_disk_size_in_gb":         &hcldec.AttrSpec{Name: "root_disk_size_in_gb", Type: cty.Number, Required: false},
-		"root_disk_storage_type":       &hcldec.AttrSpec{Name: "root_disk_storage_type", Type: cty.String, Required: false},
-		"vpc_id":                       &hcldec.AttrSpec{Name: "vpc_id", Type: cty.String, Required: false},
-		"vpc_name":                     &hcldec.AttrSpec{Name: "vpc_name", Type: cty.String, Required: false},
-		"vpc_cidr_block":               &hcldec.AttrSpec{Name: "vpc_cidr_block", Type: cty.String, Required: false},
-		"subnet_id":                    &hcldec.AttrSpec{Name: "subnet_id", Type: cty.String, Required: false},
-		"subnet_cidr_block":            &hcldec.AttrSpec{Name: "subnet_cidr_block", Type: cty.String, Required: false},
-		"subnet_name":                  &hcldec.AttrSpec{Name: "subnet_name", Type: cty.String, Required: false},
-		"keypair_id":                   &hcldec.AttrSpec{Name: "keypair_id", Type: cty.String, Required: false},
-		"run_tags":                     &hcldec.AttrSpec{Name: "run_tags", Type: cty.Map(cty.String), Required: false},
-		"user_data":                    &hcldec.AttrSpec{Name: "user_data", Type: cty.String, Required: false},
-		"user_data_file":               &hcldec.AttrSpec{Name: "user_data_file", Type: cty.String, Required: false},
-		"communicator":                 &hcldec.AttrSpec{Name: "communicator", Type: cty.String, Required: false},
-		"pause_before_connecting":      &hcldec.AttrSpec{Name: "pause_before_connecting", Type: cty.String, Required: false},
-		"ssh_host":                     &hcldec.AttrSpec{Name: "ssh_host", Type: cty.String, Required: false},
-		"ssh_port":                     &hcldec.AttrSpec{Name: "ssh_port", Type: cty.Number, Required: false},
-		"ssh_username":                 &hcldec.AttrSpec{Name: "ssh_username", Type: cty.String, Required: false},
-		"ssh_password":                 &hcldec.AttrSpec{Name: "ssh_password", Type: cty.String, Required: false},
-		"ssh_keypair_name":             &hcldec.AttrSpec{Name: "ssh_keypair_name", Type: cty.String, Required: false},
-		"temporary_key_pair_name":      &hcldec.AttrSpec{Name: "temporary_key_pair_name", Type: cty.String, Required: false},
-		"temporary_key_pair_type":      &hcldec.AttrSpec{Name: "temporary_key_pair_type", Type: cty.String, Required: false},
-		"temporary_key_pair_bits":      &hcldec.AttrSpec{Name: "temporary_key_pair_bits", Type: cty.Number, Required: false},
-		"ssh_ciphers":                  &hcldec.AttrSpec{Name: "ssh_ciphers", Type: cty.List(cty.String), Required: false},
-		"ssh_clear_authorized_keys":    &hcldec.AttrSpec{Name: "ssh_clear_authorized_keys", Type: cty.Bool, Required: false},
-		"ssh_key_exchange_algorithms":  &hcldec.AttrSpec{Name: "ssh_key_exchange_algorithms", Type: cty.List(cty.String), Required: false},
-		"ssh_private_key_file":         &hcldec.AttrSpec{Name: "ssh_private_key_file", Type: cty.String, Required: false},
-		"ssh_certificate_file":         &hcldec.AttrSpec{Name: "ssh_certificate_file", Type: cty.String, Required: false},
-		"ssh_pty":                      &hcldec.AttrSpec{Name: "ssh_pty", Type: cty.Bool, Required: false},
-		"ssh_timeout":                  &hcldec.AttrSpec{Name: "ssh_timeout", Type: cty.String, Required: false},
-		"ssh_wait_timeout":             &hcldec.AttrSpec{Name: "ssh_wait_timeout", Type: cty.String, Required: false},
-		"ssh_agent_auth":               &hcldec.AttrSpec{Name: "ssh_agent_auth", Type: cty.Bool, Required: false},
-		"ssh_disable_agent_forwarding": &hcldec.AttrSpec{Name: "ssh_disable_agent_forwarding", Type: cty.Bool, Required: false},
-		"ssh_handshake_attempts":       &hcldec.AttrSpec{Name: "ssh_handshake_attempts", Type: cty.Number, Required: false},
-		"ssh_bastion_host":             &hcldec.AttrSpec{Name: "ssh_bastion_host", Type: cty.String, Required: false},
-		"ssh_bastion_port":             &hcldec.AttrSpec{Name: "ssh_bastion_port", Type: cty.Number, Required: false},
-		"ssh_bastion_agent_auth":       &hcldec.AttrSpec{Name: "ssh_bastion_agent_auth", Type: cty.Bool, Required: false},
-		"ssh_bastion_username":         &hcldec.AttrSpec{Name: "ssh_bastion_username", Type: cty.String, Required: false},
-		"ssh_bastion_password":         &hcldec.AttrSpec{Name: "ssh_bastion_password", Type: cty.String, Required: false},
-		"ssh_bastion_interactive":      &hcldec.AttrSpec{Name: "ssh_bastion_interactive", Type: cty.Bool, Required: false},
-		"ssh_bastion_private_key_file": &hcldec.AttrSpec{Name: "ssh_bastion_private_key_file", Type: cty.String, Required: false},
-		"ssh_bastion_certificate_file": &hcldec.AttrSpec{Name: "ssh_bastion_certificate_file", Type: cty.String, Required: false},
-		"ssh_file_transfer_method":     &hcldec.AttrSpec{Name: "ssh_file_transfer_method", Type: cty.String, Required: false},
-		"ssh_proxy_host":               &hcldec.AttrSpec{Name: "ssh_proxy_host", Type: cty.String, Required: false},
-		"ssh_proxy_port":               &hcldec.AttrSpec{Name: "ssh_proxy_port", Type: cty.Number, Required: false},
-		"ssh_proxy_username":           &hcldec.AttrSpec{Name: "ssh_proxy_username", Type: cty.String, Required: false},
-		"ssh_proxy_password":           &hcldec.AttrSpec{Name: "ssh_proxy_password", Type: cty.String, Required: false},
-		"ssh_keep_alive_interval":      &hcldec.AttrSpec{Name: "ssh_keep_alive_interval", Type: cty.String, Required: false},
-		"ssh_read_write_timeout":       &hcldec.AttrSpec{Name: "ssh_read_write_timeout", Type: cty.String, Required: false},
-		"ssh_remote_tunnels":           &hcldec.AttrSpec{Name: "ssh_remote_tunnels", Type: cty.List(cty.String), Required: false},
-		"ssh_local_tunnels":            &hcldec.AttrSpec{Name: "ssh_local_tunnels", Type: cty.List(cty.String), Required: false},
-		"ssh_public_key":               &hcldec.AttrSpec{Name: "ssh_public_key", Type: cty.List(cty.Number), Required: false},
-		"ssh_private_key":              &hcldec.AttrSpec{Name: "ssh_private_key", Type: cty.List(cty.Number), Required: false},
-		"winrm_username":               &hcldec.AttrSpec{Name: "winrm_username", Type: cty.String, Required: false},
-		"winrm_password":               &hcldec.AttrSpec{Name: "winrm_password", Type: cty.String, Required: false},
-		"winrm_host":                   &hcldec.AttrSpec{Name: "winrm_host", Type: cty.String, Required: false},
-		"winrm_no_proxy":               &hcldec.AttrSpec{Name: "winrm_no_proxy", Type: cty.Bool, Required: false},
-		"winrm_port":                   &hcldec.AttrSpec{Name: "winrm_port", Type: cty.Number, Required: false},
-		"winrm_timeout":                &hcldec.AttrSpec{Name: "winrm_timeout", Type: cty.String, Required: false},
-		"winrm_use_ssl":                &hcldec.AttrSpec{Name: "winrm_use_ssl", Type: cty.Bool, Required: false},
-		"winrm_insecure":               &hcldec.AttrSpec{Name: "winrm_insecure", Type: cty.Bool, Required: false},
-		"winrm_use_ntlm":               &hcldec.AttrSpec{Name: "winrm_use_ntlm", Type: cty.Bool, Required: false},
+		"packer_build_name":                       &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
+		"packer_builder_type":                     &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
+		"packer_core_version":                     &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
+		"packer_debug":                            &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
+		"packer_force":                            &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
+		"packer_on_error":                         &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
+		"packer_user_variables":                   &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
+		"packer_sensitive_variables":              &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
+		"access_key":                              &hcldec.AttrSpec{Name: "access_key", Type: cty.String, Required: false},
+		"secret_key":                              &hcldec.AttrSpec{Name: "secret_key", Type: cty.String, Required: false},
+		"region":                                  &hcldec.AttrSpec{Name: "region", Type: cty.String, Required: false},
+		"zone":                                    &hcldec.AttrSpec{Name: "zone", Type: cty.String, Required: false},
+		"zones":                                   &hcldec.AttrSpec{Name: "zones", Type: cty.List(cty.String), Required: false},
+		"skip_region_validation":                  &hcldec.AttrSpec{Name: "skip_region_validation", Type: cty.Bool, Required: false},
+		"image_name":                              &hcldec.AttrSpec{Name: "image_name", Type: cty.String, Required: false},
+		"skip_create_image":                       &hcldec.AttrSpec{Name: "skip_create_image", Type: cty.Bool, Required: false},
+		"image_copy_regions":                      &hcldec.AttrSpec{Name: "image_copy_regions", Type: cty.List(cty.String), Required: false},
+		"image_encrypt_key_id":                    &hcldec.AttrSpec{Name: "image_encrypt_key_id", Type: cty.String, Required: false},
+		"image_copy_encrypt_key_ids":              &hcldec.AttrSpec{Name: "image_copy_encrypt_key_ids", Type: cty.Map(cty.String), Required: false},
+		"image_share_accounts":                    &hcldec.AttrSpec{Name: "image_share_accounts", Type: cty.List(cty.String), Required: false},
+		"image_share_account_ids":                 &hcldec.AttrSpec{Name: "image_share_account_ids", Type: cty.List(cty.String), Required: false},
+		"skip_image_validation":                   &hcldec.AttrSpec{Name: "skip_image_validation", Type: cty.Bool, Required: false},
+		"associate_public_ip_address":             &hcldec.AttrSpec{Name: "associate_public_ip_address", Type: cty.Bool, Required: false},
+		"temporary_nat_gateway":                   &hcldec.AttrSpec{Name: "temporary_nat_gateway", Type: cty.Bool, Required: false},
+		"temporary_nat_gateway_bandwidth_in_mbps": &hcldec.AttrSpec{Name: "temporary_nat_gateway_bandwidth_in_mbps", Type: cty.Number, Required: false},
+		"temporary_bastion":                       &hcldec.BlockSpec{TypeName: "temporary_bastion", Nested: hcldec.ObjectSpec((*FlatTemporaryBastionConfig)(nil).HCL2Spec())},
+		"use_default_network":                     &hcldec.AttrSpec{Name: "use_default_network", Type: cty.Bool, Required: false},
+		"reuse_instance_id":                       &hcldec.AttrSpec{Name: "reuse_instance_id", Type: cty.String, Required: false},
+		"source_instance_id":                      &hcldec.AttrSpec{Name: "source_instance_id", Type: cty.String, Required: false},
+		"stop_source_instance":                    &hcldec.AttrSpec{Name: "stop_source_instance", Type: cty.Bool, Required: false},
+		"instance_spec":                           &hcldec.AttrSpec{Name: "instance_spec", Type: cty.String, Required: false},
+		"instance_specs":                          &hcldec.AttrSpec{Name: "instance_specs", Type: cty.List(cty.String), Required: false},
+		"instance_name":                           &hcldec.AttrSpec{Name: "instance_name", Type: cty.String, Required: false},
+		"description":                             &hcldec.AttrSpec{Name: "description", Type: cty.String, Required: false},
+		"source_image_id":                         &hcldec.AttrSpec{Name: "source_image_id", Type: cty.String, Required: false},
+		"security_group_id":                       &hcldec.AttrSpec{Name: "security_group_id", Type: cty.String, Required: false},
+		"security_group_name":                     &hcldec.AttrSpec{Name: "security_group_name", Type: cty.String, Required: false},
+		"security_group_filter":                   &hcldec.BlockSpec{TypeName: "security_group_filter", Nested: hcldec.ObjectSpec((*FlatNetworkFilter)(nil).HCL2Spec())},
+		"security_group_ids":                      &hcldec.AttrSpec{Name: "security_group_ids", Type: cty.List(cty.String), Required: false},
+		"enterprise_security_group_ids":           &hcldec.AttrSpec{Name: "enterprise_security_group_ids", Type: cty.List(cty.String), Required: false},
+		"network_interfaces":                      &hcldec.BlockListSpec{TypeName: "network_interfaces", Nested: hcldec.ObjectSpec((*FlatNetworkInterfaceConfig)(nil).HCL2Spec())},
+		"internet_charge_type":                    &hcldec.AttrSpec{Name: "internet_charge_type", Type: cty.String, Required: false},
+		"eip_name":                                &hcldec.AttrSpec{Name: "eip_name", Type: cty.String, Required: false},
+		"network_capacity_in_mbps":                &hcldec.AttrSpec{Name: "network_capacity_in_mbps", Type: cty.Number, Required: false},
+		"spot_strategy":                           &hcldec.AttrSpec{Name: "spot_strategy", Type: cty.String, Required: false},
+		"spot_price_limit":                        &hcldec.AttrSpec{Name: "spot_price_limit", Type: cty.Number, Required: false},
+		"spot_fallback_to_on_demand":              &hcldec.AttrSpec{Name: "spot_fallback_to_on_demand", Type: cty.Bool, Required: false},
+		"deployment_set_id":                       &hcldec.AttrSpec{Name: "deployment_set_id", Type: cty.String, Required: false},
+		"auto_create_deployment_set":              &hcldec.AttrSpec{Name: "auto_create_deployment_set", Type: cty.Bool, Required: false},
+		"dedicated_host_id":                       &hcldec.AttrSpec{Name: "dedicated_host_id", Type: cty.String, Required: false},
+		"root_disk_size_in_gb":                    &hcldec.AttrSpec{Name: "root_disk_size_in_gb", Type: cty.Number, Required: false},
+		"root_disk_storage_type":                  &hcldec.AttrSpec{Name: "root_disk_storage_type", Type: cty.String, Required: false},
+		"root_disk_encrypt_key_id":                &hcldec.AttrSpec{Name: "root_disk_encrypt_key_id", Type: cty.String, Required: false},
+		"vpc_id":                                  &hcldec.AttrSpec{Name: "vpc_id", Type: cty.String, Required: false},
+		"vpc_name":                                &hcldec.AttrSpec{Name: "vpc_name", Type: cty.String, Required: false},
+		"vpc_filter":                              &hcldec.BlockSpec{TypeName: "vpc_filter", Nested: hcldec.ObjectSpec((*FlatNetworkFilter)(nil).HCL2Spec())},
+		"vpc_cidr_block":                          &hcldec.AttrSpec{Name: "vpc_cidr_block", Type: cty.String, Required: false},
+		"enable_ipv6":                             &hcldec.AttrSpec{Name: "enable_ipv6", Type: cty.Bool, Required: false},
+		"ssh_interface":                           &hcldec.AttrSpec{Name: "ssh_interface", Type: cty.String, Required: false},
+		"avoid_cidrs":                             &hcldec.AttrSpec{Name: "avoid_cidrs", Type: cty.List(cty.String), Required: false},
+		"subnet_id":                               &hcldec.AttrSpec{Name: "subnet_id", Type: cty.String, Required: false},
+		"subnet_filter":                           &hcldec.BlockSpec{TypeName: "subnet_filter", Nested: hcldec.ObjectSpec((*FlatNetworkFilter)(nil).HCL2Spec())},
+		"subnet_cidr_block":                       &hcldec.AttrSpec{Name: "subnet_cidr_block", Type: cty.String, Required: false},
+		"subnet_cidr_prefix_length":               &hcldec.AttrSpec{Name: "subnet_cidr_prefix_length", Type: cty.Number, Required: false},
+		"subnet_name":                             &hcldec.AttrSpec{Name: "subnet_name", Type: cty.String, Required: false},
+		"keypair_id":                              &hcldec.AttrSpec{Name: "keypair_id", Type: cty.String, Required: false},
+		"import_temporary_key_pair":               &hcldec.AttrSpec{Name: "import_temporary_key_pair", Type: cty.Bool, Required: false},
+		"ssh_public_key_file":                     &hcldec.AttrSpec{Name: "ssh_public_key_file", Type: cty.String, Required: false},
+		"run_tags":                                &hcldec.AttrSpec{Name: "run_tags", Type: cty.Map(cty.String), Required: false},
+		"user_data":                               &hcldec.AttrSpec{Name: "user_data", Type: cty.String, Required: false},
+		"user_data_file":                          &hcldec.AttrSpec{Name: "user_data_file", Type: cty.String, Required: false},
+		"user_data_files":                         &hcldec.AttrSpec{Name: "user_data_files", Type: cty.List(cty.String), Required: false},
+		"wait_for_user_data":                      &hcldec.AttrSpec{Name: "wait_for_user_data", Type: cty.Bool, Required: false},
+		"user_data_timeout":                       &hcldec.AttrSpec{Name: "user_data_timeout", Type: cty.String, Required: false},
+		"ssh_temporary_password":                  &hcldec.AttrSpec{Name: "ssh_temporary_password", Type: cty.Bool, Required: false},
+		"reset_temporary_password":                &hcldec.AttrSpec{Name: "reset_temporary_password", Type: cty.Bool, Required: false},
+		"windows_sysprep":                         &hcldec.AttrSpec{Name: "windows_sysprep", Type: cty.Bool, Required: false},
+		"skip_instance_quota_check":               &hcldec.AttrSpec{Name: "skip_instance_quota_check", Type: cty.Bool, Required: false},
+		"skip_vpc_quota_check":                    &hcldec.AttrSpec{Name: "skip_vpc_quota_check", Type: cty.Bool, Required: false},
+		"skip_subnet_quota_check":                 &hcldec.AttrSpec{Name: "skip_subnet_quota_check", Type: cty.Bool, Required: false},
+		"skip_security_group_quota_check":         &hcldec.AttrSpec{Name: "skip_security_group_quota_check", Type: cty.Bool, Required: false},
+		"skip_eip_quota_check":                    &hcldec.AttrSpec{Name: "skip_eip_quota_check", Type: cty.Bool, Required: false},
+		"skip_image_quota_check":                  &hcldec.AttrSpec{Name: "skip_image_quota_check", Type: cty.Bool, Required: false},
+		"check_balance":                           &hcldec.AttrSpec{Name: "check_balance", Type: cty.Bool, Required: false},
+		"dry_run":                                 &hcldec.AttrSpec{Name: "dry_run", Type: cty.Bool, Required: false},
+		"communicator":                            &hcldec.AttrSpec{Name: "communicator", Type: cty.String, Required: false},
+		"pause_before_connecting":                 &hcldec.AttrSpec{Name: "pause_before_connecting", Type: cty.String, Required: false},
+		"ssh_host":                                &hcldec.AttrSpec{Name: "ssh_host", Type: cty.String, Required: false},
+		"ssh_port":                                &hcldec.AttrSpec{Name: "ssh_port", Type: cty.Number, Required: false},
+		"ssh_username":                            &hcldec.AttrSpec{Name: "ssh_username", Type: cty.String, Required: false},
+		"ssh_password":                            &hcldec.AttrSpec{Name: "ssh_password", Type: cty.String, Required: false},
+		"ssh_keypair_name":                        &hcldec.AttrSpec{Name: "ssh_keypair_name", Type: cty.String, Required: false},
+		"temporary_key_pair_name":                 &hcldec.AttrSpec{Name: "temporary_key_pair_name", Type: cty.String, Required: false},
+		"temporary_key_pair_type":                 &hcldec.AttrSpec{Name: "temporary_key_pair_type", Type: cty.String, Required: false},
+		"temporary_key_pair_bits":                 &hcldec.AttrSpec{Name: "temporary_key_pair_bits", Type: cty.Number, Required: false},
+		"ssh_ciphers":                             &hcldec.AttrSpec{Name: "ssh_ciphers", Type: cty.List(cty.String), Required: false},
+		"ssh_clear_authorized_keys":               &hcldec.AttrSpec{Name: "ssh_clear_authorized_keys", Type: cty.Bool, Required: false},
+		"ssh_key_exchange_algorithms":             &hcldec.AttrSpec{Name: "ssh_key_exchange_algorithms", Type: cty.List(cty.String), Required: false},
+		"ssh_private_key_file":                    &hcldec.AttrSpec{Name: "ssh_private_key_file", Type: cty.String, Required: false},
+		"ssh_certificate_file":                    &hcldec.AttrSpec{Name: "ssh_certificate_file", Type: cty.String, Required: false},
+		"ssh_pty":                                 &hcldec.AttrSpec{Name: "ssh_pty", Type: cty.Bool, Required: false},
+		"ssh_timeout":                             &hcldec.AttrSpec{Name: "ssh_timeout", Type: cty.String, Required: false},
+		"ssh_wait_timeout":                        &hcldec.AttrSpec{Name: "ssh_wait_timeout", Type: cty.String, Required: false},
+		"ssh_agent_auth":                          &hcldec.AttrSpec{Name: "ssh_agent_auth", Type: cty.Bool, Required: false},
+		"ssh_disable_agent_forwarding":            &hcldec.AttrSpec{Name: "ssh_disable_agent_forwarding", Type: cty.Bool, Required: false},
+		"ssh_handshake_attempts":                  &hcldec.AttrSpec{Name: "ssh_handshake_attempts", Type: cty.Number, Required: false},
+		"ssh_bastion_host":                        &hcldec.AttrSpec{Name: "ssh_bastion_host", Type: cty.String, Required: false},
+		"ssh_bastion_port":                        &hcldec.AttrSpec{Name: "ssh_bastion_port", Type: cty.Number, Required: false},
+		"ssh_bastion_agent_auth":                  &hcldec.AttrSpec{Name: "ssh_bastion_agent_auth", Type: cty.Bool, Required: false},
+		"ssh_bastion_username":                    &hcldec.AttrSpec{Name: "ssh_bastion_username", Type: cty.String, Required: false},
+		"ssh_bastion_password":                    &hcldec.AttrSpec{Name: "ssh_bastion_password", Type: cty.String, Required: false},
+		"ssh_bastion_interactive":                 &hcldec.AttrSpec{Name: "ssh_bastion_interactive", Type: cty.Bool, Required: false},
+		"ssh_bastion_private_key_file":            &hcldec.AttrSpec{Name: "ssh_bastion_private_key_file", Type: cty.String, Required: false},
+		"ssh_bastion_certificate_file":            &hcldec.AttrSpec{Name: "ssh_bastion_certificate_file", Type: cty.String, Required: false},
+		"ssh_file_transfer_method":                &hcldec.AttrSpec{Name: "ssh_file_transfer_method", Type: cty.String, Required: false},
+		"ssh_proxy_host":                          &hcldec.AttrSpec{Name: "ssh_proxy_host", Type: cty.String, Required: false},
+		"ssh_proxy_port":                          &hcldec.AttrSpec{Name: "ssh_proxy_port", Type: cty.Number, Required: false},
+		"ssh_proxy_username":                      &hcldec.AttrSpec{Name: "ssh_proxy_username", Type: cty.String, Required: false},
+		"ssh_proxy_password":                      &hcldec.AttrSpec{Name: "ssh_proxy_password", Type: cty.String, Required: false},
+		"ssh_keep_alive_interval":                 &hcldec.AttrSpec{Name: "ssh_keep_alive_interval", Type: cty.String, Required: false},
+		"ssh_read_write_timeout":                  &hcldec.AttrSpec{Name: "ssh_read_write_timeout", Type: cty.String, Required: false},
+		"ssh_remote_tunnels":                      &hcldec.AttrSpec{Name: "ssh_remote_tunnels", Type: cty.List(cty.String), Required: false},
+		"ssh_local_tunnels":                       &hcldec.AttrSpec{Name: "ssh_local_tunnels", Type: cty.List(cty.String), Required: false},
+		"ssh_public_key":                          &hcldec.AttrSpec{Name: "ssh_public_key", Type: cty.List(cty.Number), Required: false},
+		"ssh_private_key":                         &hcldec.AttrSpec{Name: "ssh_private_key", Type: cty.List(cty.Number), Required: false},
+		"winrm_username":                          &hcldec.AttrSpec{Name: "winrm_username", Type: cty.String, Required: false},
+		"winrm_password":                          &hcldec.AttrSpec{Name: "winrm_password", Type: cty.String, Required: false},
+		"winrm_host":                              &hcldec.AttrSpec{Name: "winrm_host", Type: cty.String, Required: false},
+		"winrm_no_proxy":                          &hcldec.AttrSpec{Name: "winrm_no_proxy", Type: cty.Bool, Required: false},
+		"winrm_port":                              &hcldec.AttrSpec{Name: "winrm_port", Type: cty.Number, Required: false},
+		"winrm_timeout":                           &hcldec.AttrSpec{Name: "winrm_timeout", Type: cty.String, Required: false},
+		"winrm_use_ssl":                           &hcldec.AttrSpec{Name: "winrm_use_ssl", Type: cty.Bool, Required: false},
+		"winrm_insecure":                          &hcldec.AttrSpec{Name: "winrm_insecure", Type: cty.Bool, Required: false},
+		"winrm_use_ntlm":                          &hcldec.AttrSpec{Name: "winrm_use_ntlm", Type: cty.Bool, Required: false},
+	}
+	return s
+}
+
+// FlatNetworkFilter is an auto-generated flat version of NetworkFilter.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatNetworkFilter struct {
+	Name        *string           `mapstructure:"name" required:"false" cty:"name" hcl:"name"`
+	Tags        map[string]string `mapstructure:"tags" required:"false" cty:"tags" hcl:"tags"`
+	Cidr        *string           `mapstructure:"cidr" required:"false" cty:"cidr" hcl:"cidr"`
+	Zone        *string           `mapstructure:"zone" required:"false" cty:"zone" hcl:"zone"`
+	MostFreeIps *bool             `mapstructure:"most_free_ips" required:"false" cty:"most_free_ips" hcl:"most_free_ips"`
+}
+
+// FlatMapstructure returns a new FlatNetworkFilter.
+// FlatNetworkFilter is an auto-generated flat version of NetworkFilter.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*NetworkFilter) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatNetworkFilter)
+}
+
+// HCL2Spec returns the hcl spec of a NetworkFilter.
+// This spec is used by HCL to read the fields of NetworkFilter.
+// The decoded values from this spec will then be applied to a FlatNetworkFilter.
+func (*FlatNetworkFilter) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"name":          &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"tags":          &hcldec.AttrSpec{Name: "tags", Type: cty.Map(cty.String), Required: false},
+		"cidr":          &hcldec.AttrSpec{Name: "cidr", Type: cty.String, Required: false},
+		"zone":          &hcldec.AttrSpec{Name: "zone", Type: cty.String, Required: false},
+		"most_free_ips": &hcldec.AttrSpec{Name: "most_free_ips", Type: cty.Bool, Required: false},
+	}
+	return s
+}
+
+// FlatNetworkInterfaceConfig is an auto-generated flat version of NetworkInterfaceConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatNetworkInterfaceConfig struct {
+	SubnetId                *string  `mapstructure:"subnet_id" required:"false" cty:"subnet_id" hcl:"subnet_id"`
+	SecurityGroupIds        []string `mapstructure:"security_group_ids" required:"false" cty:"security_group_ids" hcl:"security_group_ids"`
+	SecondaryPrivateIpCount *int     `mapstructure:"secondary_private_ip_count" required:"false" cty:"secondary_private_ip_count" hcl:"secondary_private_ip_count"`
+}
+
+// FlatMapstructure returns a new FlatNetworkInterfaceConfig.
+// FlatNetworkInterfaceConfig is an auto-generated flat version of NetworkInterfaceConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*NetworkInterfaceConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatNetworkInterfaceConfig)
+}
+
+// HCL2Spec returns the hcl spec of a NetworkInterfaceConfig.
+// This spec is used by HCL to read the fields of NetworkInterfaceConfig.
+// The decoded values from this spec will then be applied to a FlatNetworkInterfaceConfig.
+func (*FlatNetworkInterfaceConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"subnet_id":                  &hcldec.AttrSpec{Name: "subnet_id", Type: cty.String, Required: false},
+		"security_group_ids":         &hcldec.AttrSpec{Name: "security_group_ids", Type: cty.List(cty.String), Required: false},
+		"secondary_private_ip_count": &hcldec.AttrSpec{Name: "secondary_private_ip_count", Type: cty.Number, Required: false},
+	}
+	return s
+}
+
+// FlatTemporaryBastionConfig is an auto-generated flat version of TemporaryBastionConfig.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatTemporaryBastionConfig struct {
+	InstanceSpec          *string `mapstructure:"instance_spec" required:"true" cty:"instance_spec" hcl:"instance_spec"`
+	SourceImageId         *string `mapstructure:"source_image_id" required:"false" cty:"source_image_id" hcl:"source_image_id"`
+	SSHUsername           *string `mapstructure:"ssh_username" required:"false" cty:"ssh_username" hcl:"ssh_username"`
+	NetworkCapacityInMbps *int    `mapstructure:"network_capacity_in_mbps" required:"false" cty:"network_capacity_in_mbps" hcl:"network_capacity_in_mbps"`
+}
+
+// FlatMapstructure returns a new FlatTemporaryBastionConfig.
+// FlatTemporaryBastionConfig is an auto-generated flat version of TemporaryBastionConfig.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*TemporaryBastionConfig) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatTemporaryBastionConfig)
+}
+
+// HCL2Spec returns the hcl spec of a TemporaryBastionConfig.
+// This spec is used by HCL to read the fields of TemporaryBastionConfig.
+// The decoded values from this spec will then be applied to a FlatTemporaryBastionConfig.
+func (*FlatTemporaryBastionConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"instance_spec":            &hcldec.AttrSpec{Name: "instance_spec", Type: cty.String, Required: false},
+		"source_image_id":          &hcldec.AttrSpec{Name: "source_image_id", Type: cty.String, Required: false},
+		"ssh_username":             &hcldec.AttrSpec{Name: "ssh_username", Type: cty.String, Required: false},
+		"network_capacity_in_mbps": &hcldec.AttrSpec{Name: "network_capacity_in_mbps", Type: cty.Number, Required: false},
 	}
 	return s
 }
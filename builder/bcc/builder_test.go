@@ -93,3 +93,76 @@ func TestBuidlerPrepare_InvalidKey(t *testing.T) {
 		t.Fatalf("Should raise error")
 	}
 }
+
+func TestBuilderPrepare_KeypairIdWithSSHKeyPairName(t *testing.T) {
+	var b Builder
+	config := testBuilderConfig()
+
+	config["keypair_id"] = "k-test"
+	config["ssh_keypair_name"] = "packer"
+	_, warnings, err := b.Prepare(config)
+	if err != nil {
+		t.Fatalf("Shouldn't raise error: %s", err)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("Should warn that 'ssh_keypair_name' is ignored: %#v", warnings)
+	}
+}
+
+func TestBuilderPrepare_ImageEncryptKeyId(t *testing.T) {
+	var b Builder
+	config := testBuilderConfig()
+
+	config["image_encrypt_key_id"] = "key-fwh"
+	config["image_copy_regions"] = []string{"fwh", "bj", "gz"}
+	config["image_copy_encrypt_key_ids"] = map[string]string{"bj": "key-bj"}
+	_, _, err := b.Prepare(config)
+	if err == nil {
+		t.Fatal("Should raise error")
+	}
+	if errs := err.(*packersdk.MultiError).Errors; len(errs) != 1 {
+		t.Fatalf("Should raise an error for region gz only: %s", errs)
+	}
+
+	// no key is needed for the source region
+	config["image_copy_encrypt_key_ids"] = map[string]string{"bj": "key-bj", "gz": "key-gz"}
+	b = Builder{}
+	if _, _, err = b.Prepare(config); err != nil {
+		t.Fatalf("Shouldn't raise error: %s", err)
+	}
+}
+
+func TestBuilderPrepare_DedicatedHost(t *testing.T) {
+	var b Builder
+	config := testBuilderConfig()
+
+	config["dedicated_host_id"] = "d-test"
+	_, warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("Shouldn't raise error: %s", err)
+	}
+
+	config["zones"] = []string{"cn-fwh-a", "cn-fwh-b"}
+	b = Builder{}
+	_, warnings, err = b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err == nil {
+		t.Fatal("Should raise error")
+	}
+
+	// the zone is derived from the subnet
+	config = testBuilderConfig()
+	config["dedicated_host_id"] = "d-test"
+	delete(config, "zone")
+	config["vpc_id"] = "vpc-test"
+	config["subnet_id"] = "sbn-test"
+	b = Builder{}
+	if _, _, err = b.Prepare(config); err != nil {
+		t.Fatalf("Shouldn't raise error: %s", err)
+	}
+}
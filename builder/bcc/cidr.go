@@ -0,0 +1,122 @@
+package bcc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/baidubce/bce-sdk-go/services/vpc"
+)
+
+// cidrAuto makes the build pick a free cidr block for the temporary subnet
+const cidrAuto = "auto"
+
+// defaultVpcCidrBlock is the cidr block of the temporary vpc, unless it
+// overlaps `avoid_cidrs`
+const defaultVpcCidrBlock = "192.168.0.0/16"
+
+// defaultSubnetCidrBlock is the cidr block of the temporary subnet in the
+// temporary vpc of defaultVpcCidrBlock
+const defaultSubnetCidrBlock = "192.168.8.0/24"
+
+// vpcCidrCandidates returns the private /16 blocks, which are tried in order
+// as the cidr block of the temporary vpc
+func vpcCidrCandidates() []string {
+	candidates := []string{defaultVpcCidrBlock}
+	for i := 16; i < 32; i++ {
+		candidates = append(candidates, fmt.Sprintf("172.%d.0.0/16", i))
+	}
+	for i := 0; i < 256; i++ {
+		candidates = append(candidates, fmt.Sprintf("10.%d.0.0/16", i))
+	}
+	return candidates
+}
+
+// parseCidrs parses the cidr blocks
+func parseCidrs(cidrs []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		if ipNet.IP.To4() == nil {
+			return nil, fmt.Errorf("%s isn't an ipv4 cidr block", cidr)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// cidrsOverlap reports whether the two blocks share any address
+func cidrsOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// overlapsAny returns the first block of nets which overlaps ipNet
+func overlapsAny(ipNet *net.IPNet, nets []*net.IPNet) *net.IPNet {
+	for _, n := range nets {
+		if cidrsOverlap(ipNet, n) {
+			return n
+		}
+	}
+	return nil
+}
+
+// pickVpcCidr returns the first candidate block of the temporary vpc which
+// doesn't overlap the avoided blocks
+func pickVpcCidr(avoid []*net.IPNet) (string, error) {
+	for _, candidate := range vpcCidrCandidates() {
+		_, ipNet, _ := net.ParseCIDR(candidate)
+		if overlapsAny(ipNet, avoid) == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("all the private cidr blocks overlap 'avoid_cidrs'")
+}
+
+// pickSubnetCidr returns the first block of the prefix length in the vpc
+// block, which doesn't overlap the used blocks
+func pickSubnetCidr(vpcCidr string, prefixLength int, used []*net.IPNet) (string, error) {
+	_, vpcNet, err := net.ParseCIDR(vpcCidr)
+	if err != nil {
+		return "", err
+	}
+	vpcPrefixLength, bits := vpcNet.Mask.Size()
+	if bits != 32 {
+		return "", fmt.Errorf("the cidr block %s of vpc isn't ipv4", vpcCidr)
+	}
+	if prefixLength < vpcPrefixLength || prefixLength > 32 {
+		return "", fmt.Errorf("the prefix length %d doesn't fit in the cidr block %s of vpc", prefixLength, vpcCidr)
+	}
+
+	start := binary.BigEndian.Uint32(vpcNet.IP.To4())
+	size := uint32(1) << uint(32-prefixLength)
+	count := uint64(1) << uint(prefixLength-vpcPrefixLength)
+	for i := uint64(0); i < count; i++ {
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, start+uint32(i)*size)
+		ipNet := &net.IPNet{IP: ip, Mask: net.CIDRMask(prefixLength, 32)}
+		if overlapsAny(ipNet, used) == nil {
+			return ipNet.String(), nil
+		}
+	}
+	return "", fmt.Errorf("no free /%d block is left in the cidr block %s of vpc", prefixLength, vpcCidr)
+}
+
+// pickFreeSubnetCidr picks the first block of the prefix length in the vpc,
+// which overlaps neither the subnets of the vpc nor the avoided blocks
+func pickFreeSubnetCidr(v *vpc.ShowVPCModel, prefixLength int, avoidCidrs []string) (string, error) {
+	used, err := parseCidrs(avoidCidrs)
+	if err != nil {
+		return "", err
+	}
+	for _, subnet := range v.Subnets {
+		subnetCidrs, err := parseCidrs([]string{subnet.Cidr})
+		if err != nil {
+			return "", err
+		}
+		used = append(used, subnetCidrs...)
+	}
+	return pickSubnetCidr(v.Cidr, prefixLength, used)
+}
@@ -0,0 +1,70 @@
+package bcc
+
+import (
+	"testing"
+
+	"github.com/baidubce/bce-sdk-go/services/vpc"
+)
+
+func TestPickVpcCidr(t *testing.T) {
+	cidr, err := pickVpcCidr(nil)
+	if err != nil || cidr != defaultVpcCidrBlock {
+		t.Fatalf("Picked cidr is wrong: %s, %s", cidr, err)
+	}
+
+	avoid, _ := parseCidrs([]string{"192.168.100.0/24", "172.16.0.0/12"})
+	cidr, err = pickVpcCidr(avoid)
+	if err != nil || cidr != "10.0.0.0/16" {
+		t.Fatalf("Picked cidr is wrong: %s, %s", cidr, err)
+	}
+
+	avoid, _ = parseCidrs([]string{"192.168.0.0/16", "172.16.0.0/12", "10.0.0.0/8"})
+	if _, err := pickVpcCidr(avoid); err == nil {
+		t.Fatalf("Should raise an error")
+	}
+}
+
+func TestPickSubnetCidr(t *testing.T) {
+	used, _ := parseCidrs([]string{"192.168.0.0/24", "192.168.1.0/25", "192.168.3.0/24"})
+	cidr, err := pickSubnetCidr("192.168.0.0/16", 24, used)
+	if err != nil || cidr != "192.168.2.0/24" {
+		t.Fatalf("Picked cidr is wrong: %s, %s", cidr, err)
+	}
+
+	used, _ = parseCidrs([]string{"192.168.0.0/16"})
+	if _, err := pickSubnetCidr("192.168.0.0/16", 24, used); err == nil {
+		t.Fatalf("Should raise an error")
+	}
+	if _, err := pickSubnetCidr("192.168.0.0/16", 8, nil); err == nil {
+		t.Fatalf("Should raise an error")
+	}
+}
+
+func TestPickFreeSubnetCidr(t *testing.T) {
+	v := &vpc.ShowVPCModel{
+		Cidr: "192.168.0.0/16",
+		Subnets: []vpc.Subnet{
+			{SubnetId: "sbn-a", Cidr: "192.168.0.0/24"},
+			{SubnetId: "sbn-b", Cidr: "192.168.1.0/24"},
+		},
+	}
+	cidr, err := pickFreeSubnetCidr(v, 24, nil)
+	if err != nil || cidr != "192.168.2.0/24" {
+		t.Fatalf("Picked cidr is wrong: %s, %s", cidr, err)
+	}
+
+	// the avoided blocks are skipped along with the existing subnets
+	cidr, err = pickFreeSubnetCidr(v, 24, []string{"192.168.2.0/23"})
+	if err != nil || cidr != "192.168.4.0/24" {
+		t.Fatalf("Picked cidr is wrong: %s, %s", cidr, err)
+	}
+
+	if _, err := pickFreeSubnetCidr(v, 24, []string{"invalid"}); err == nil {
+		t.Fatalf("Should raise an error")
+	}
+
+	v.Subnets = append(v.Subnets, vpc.Subnet{SubnetId: "sbn-c", Cidr: "192.168.0.0/16"})
+	if _, err := pickFreeSubnetCidr(v, 24, nil); err == nil {
+		t.Fatalf("Should raise an error")
+	}
+}
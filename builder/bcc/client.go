@@ -1,11 +1,18 @@
 package bcc
 
 import (
+	"github.com/baidubce/bce-sdk-go/bce"
 	"github.com/baidubce/bce-sdk-go/services/bcc"
+	"github.com/baidubce/bce-sdk-go/services/dcc"
 	"github.com/baidubce/bce-sdk-go/services/eip"
+	"github.com/baidubce/bce-sdk-go/services/eni"
+	"github.com/baidubce/bce-sdk-go/services/quotacenter"
 	"github.com/baidubce/bce-sdk-go/services/vpc"
 )
 
+// billingEndpoint is the global endpoint of the billing service
+const billingEndpoint = "https://billing.baidubce.com"
+
 func newBccClient(ak string, sk string, endpoint string) (*bcc.Client, error) {
 	return bcc.NewClient(ak, sk, endpoint)
 }
@@ -17,3 +24,19 @@ func newVpcClient(ak string, sk string, endpoint string) (*vpc.Client, error) {
 func newEipClient(ak string, sk string, endpoint string) (*eip.Client, error) {
 	return eip.NewClient(ak, sk, endpoint)
 }
+
+func newDccClient(ak string, sk string, endpoint string) (*dcc.Client, error) {
+	return dcc.NewClient(ak, sk, endpoint)
+}
+
+func newQuotaCenterClient(ak string, sk string) (*quotacenter.Client, error) {
+	return quotacenter.NewClient(ak, sk, "")
+}
+
+func newBillingClient(ak string, sk string) (bce.Client, error) {
+	return bce.NewBceClientWithAkSk(ak, sk, billingEndpoint)
+}
+
+func newEniClient(ak string, sk string, endpoint string) (*eni.Client, error) {
+	return eni.NewClient(ak, sk, endpoint)
+}
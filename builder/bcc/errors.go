@@ -0,0 +1,157 @@
+package bcc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/baidubce/bce-sdk-go/bce"
+)
+
+// ErrorKind classifies an error returned by baiducloud services by what the
+// user can do about it
+type ErrorKind string
+
+const (
+	ErrorKindUnknown    ErrorKind = "unknown"
+	ErrorKindQuota      ErrorKind = "quota"
+	ErrorKindCapacity   ErrorKind = "capacity"
+	ErrorKindAuth       ErrorKind = "auth"
+	ErrorKindBilling    ErrorKind = "billing"
+	ErrorKindValidation ErrorKind = "validation"
+	ErrorKindTransient  ErrorKind = "transient"
+	ErrorKindInUse      ErrorKind = "in use"
+)
+
+// ServiceError is a classified `BceServiceError` along with a hint about how
+// to fix the problem
+type ServiceError struct {
+	Kind ErrorKind
+	Hint string
+	Err  *bce.BceServiceError
+}
+
+func (e *ServiceError) Error() string {
+	if e.Hint == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s (%s error: %s)", e.Err.Error(), e.Kind, e.Hint)
+}
+
+func (e *ServiceError) Unwrap() error {
+	return e.Err
+}
+
+type errorCatalogEntry struct {
+	kind ErrorKind
+	hint string
+}
+
+const (
+	hintQuota = "the quota of your account is exhausted, release unused resources or " +
+		"apply for a higher quota in the baiducloud console"
+	hintCapacity = "the instance spec is sold out in the zone, try another 'instance_spec' or 'zone' later"
+	hintAuth     = "please check 'access_key', 'secret_key' and the IAM permissions of the account"
+	hintBilling  = "the account balance is insufficient or in arrears, please recharge the account"
+	hintRetry    = "the service is busy, please try again later"
+	hintInUse    = "a dependent resource is still in use, please release it and delete the resource manually"
+)
+
+// errorCatalog maps the known error codes of baiducloud services to their kind
+var errorCatalog = map[string]errorCatalogEntry{
+	// resources with dependencies still attached, which may be released
+	// soon while cleaning up
+	"Instance.DeleteServerFailException":                            {ErrorKindInUse, hintInUse},
+	"SecurityGroup.InstancesAssociatedSecurityGroupCanNotBeDeleted": {ErrorKindInUse, hintInUse},
+	"SECURITYGROUP_INUSE":                                           {ErrorKindInUse, hintInUse},
+	"NotAllowDeleteVpc":                                             {ErrorKindInUse, hintInUse},
+	"NotAllowOperateSubnet":                                         {ErrorKindInUse, hintInUse},
+	"ResourceNeedRelease":                                           {ErrorKindInUse, hintInUse},
+	"ServiceInternalError":                                          {ErrorKindTransient, hintRetry},
+	"RateLimit":                                                     {ErrorKindTransient, hintRetry},
+	"RequestLimitExceeded":                                          {ErrorKindTransient, hintRetry},
+	bce.EINTERNAL_ERROR:                                             {ErrorKindTransient, hintRetry},
+	"Instance.InstanceQuotaExceeded":                                {ErrorKindQuota, hintQuota},
+	"Instance.CpuQuotaExceeded":                                     {ErrorKindQuota, hintQuota},
+	"Image.CustomImageQuotaExceeded":                                {ErrorKindQuota, hintQuota},
+	"Keypair.KeypairQuotaExceeded":                                  {ErrorKindQuota, hintQuota},
+	"SecurityGroup.QuotaExceeded":                                   {ErrorKindQuota, hintQuota},
+	"VpcQuotaExceeded":                                              {ErrorKindQuota, hintQuota},
+	"SubnetQuotaExceeded":                                           {ErrorKindQuota, hintQuota},
+	"EipQuotaExceeded":                                              {ErrorKindQuota, hintQuota},
+	"Instance.NoStock":                                              {ErrorKindCapacity, hintCapacity},
+	"Instance.SpecSoldOut":                                          {ErrorKindCapacity, hintCapacity},
+	"Instance.ResourceNotEnough":                                    {ErrorKindCapacity, hintCapacity},
+	"Instance.ZoneResourceInsufficient":                             {ErrorKindCapacity, hintCapacity},
+	"Instance.BidPriceTooLow":                                       {ErrorKindCapacity, "the 'spot_price_limit' is lower than the market price, raise it or set 'spot_fallback_to_on_demand'"},
+	"Instance.BidStockNotEnough":                                    {ErrorKindCapacity, "spot instances are sold out, try later or set 'spot_fallback_to_on_demand'"},
+	"Account.Arrears":                                               {ErrorKindBilling, hintBilling},
+	"AccountArrears":                                                {ErrorKindBilling, hintBilling},
+	"Instance.InsufficientBalance":                                  {ErrorKindBilling, hintBilling},
+	"InsufficientBalance":                                           {ErrorKindBilling, hintBilling},
+	bce.EACCESS_DENIED:                                              {ErrorKindAuth, hintAuth},
+	bce.EINVALID_ACCESS_KEY_ID:                                      {ErrorKindAuth, hintAuth},
+	bce.ESIGNATURE_DOES_NOT_MATCH:                                   {ErrorKindAuth, hintAuth},
+	bce.EREQUEST_EXPIRED:                                            {ErrorKindAuth, "the request is expired, please check the clock of the build host"},
+	bce.EOPT_IN_REQUIRED:                                            {ErrorKindAuth, "the service is not activated for the account, please activate it in the baiducloud console"},
+	"Instance.InvalidSpec":                                          {ErrorKindValidation, "the 'instance_spec' doesn't exist in the region, please check it"},
+	"Instance.ZoneNotMatchSubnet":                                   {ErrorKindValidation, "the subnet doesn't belong to the 'zone', please choose a subnet of the zone"},
+	"Subnet.ZoneMismatch":                                           {ErrorKindValidation, "the subnet doesn't belong to the 'zone', please choose a subnet of the zone"},
+	"Image.ImageNotExist":                                           {ErrorKindValidation, "the 'source_image_id' doesn't exist in the region, please check it"},
+	bce.EMALFORMED_JSON:                                             {ErrorKindValidation, ""},
+	bce.EINAPPROPRIATE_JSON:                                         {ErrorKindValidation, ""},
+}
+
+// ClassifyError wraps err into a `ServiceError` if it is a `BceServiceError`,
+// otherwise err is returned as is
+func ClassifyError(err error) error {
+	if bceErr, ok := err.(*bce.BceServiceError); ok {
+		return classifyServiceError(bceErr)
+	}
+	return err
+}
+
+// ErrorKindOf returns the kind of the service error wrapped in err, or
+// `ErrorKindUnknown` if there is none
+func ErrorKindOf(err error) ErrorKind {
+	var serviceErr *ServiceError
+	if errors.As(err, &serviceErr) {
+		return serviceErr.Kind
+	}
+	var bceErr *bce.BceServiceError
+	if errors.As(err, &bceErr) {
+		return classifyServiceError(bceErr).Kind
+	}
+	return ErrorKindUnknown
+}
+
+// classifyServiceError looks up the code of err in the catalog. Errors with
+// unknown codes are classified by the keywords of the code and the http
+// status code.
+func classifyServiceError(err *bce.BceServiceError) *ServiceError {
+	if entry, ok := errorCatalog[err.Code]; ok {
+		return &ServiceError{Kind: entry.kind, Hint: entry.hint, Err: err}
+	}
+
+	code := strings.ToLower(err.Code)
+	switch {
+	case strings.Contains(code, "quota"):
+		return &ServiceError{Kind: ErrorKindQuota, Hint: hintQuota, Err: err}
+	case strings.Contains(code, "stock") || strings.Contains(code, "soldout"):
+		return &ServiceError{Kind: ErrorKindCapacity, Hint: hintCapacity, Err: err}
+	case strings.Contains(code, "arrear") || strings.Contains(code, "balance"):
+		return &ServiceError{Kind: ErrorKindBilling, Hint: hintBilling, Err: err}
+	}
+
+	switch {
+	case err.StatusCode == http.StatusUnauthorized || err.StatusCode == http.StatusForbidden:
+		return &ServiceError{Kind: ErrorKindAuth, Hint: hintAuth, Err: err}
+	case err.StatusCode == http.StatusTooManyRequests || err.StatusCode >= http.StatusInternalServerError:
+		return &ServiceError{Kind: ErrorKindTransient, Hint: hintRetry, Err: err}
+	case err.StatusCode == http.StatusBadRequest:
+		return &ServiceError{Kind: ErrorKindValidation, Err: err}
+	}
+
+	return &ServiceError{Kind: ErrorKindUnknown, Err: err}
+}
@@ -0,0 +1,59 @@
+package bcc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/baidubce/bce-sdk-go/bce"
+)
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		err  error
+		kind ErrorKind
+	}{
+		{bce.NewBceServiceError("RateLimit", "", "", http.StatusBadRequest), ErrorKindTransient},
+		{bce.NewBceServiceError("NotAllowDeleteVpc", "", "", http.StatusBadRequest), ErrorKindInUse},
+		{bce.NewBceServiceError("Instance.NoStock", "", "", http.StatusBadRequest), ErrorKindCapacity},
+		{bce.NewBceServiceError("Image.CustomImageQuotaExceeded", "", "", http.StatusBadRequest), ErrorKindQuota},
+		{bce.NewBceServiceError("Vpc.SubnetQuotaExhausted", "", "", http.StatusBadRequest), ErrorKindQuota},
+		{bce.NewBceServiceError("Account.Arrears", "", "", http.StatusBadRequest), ErrorKindBilling},
+		{bce.NewBceServiceError("AccessDenied", "", "", http.StatusForbidden), ErrorKindAuth},
+		{bce.NewBceServiceError("Unknown", "", "", http.StatusUnauthorized), ErrorKindAuth},
+		{bce.NewBceServiceError("Unknown", "", "", http.StatusServiceUnavailable), ErrorKindTransient},
+		{bce.NewBceServiceError("Unknown", "", "", http.StatusBadRequest), ErrorKindValidation},
+		{bce.NewBceServiceError("Unknown", "", "", http.StatusNotFound), ErrorKindUnknown},
+		{errors.New("not a service error"), ErrorKindUnknown},
+	}
+
+	for _, c := range cases {
+		if kind := ErrorKindOf(c.err); kind != c.kind {
+			t.Fatalf("Error %s should be classified as %s, got %s", c.err, c.kind, kind)
+		}
+	}
+}
+
+func TestClassifyError_Hint(t *testing.T) {
+	bceErr := bce.NewBceServiceError("Instance.NoStock", "sold out", "", http.StatusBadRequest)
+
+	var serviceErr *ServiceError
+	if !errors.As(ClassifyError(bceErr), &serviceErr) {
+		t.Fatalf("Should be a ServiceError: %s", ClassifyError(bceErr))
+	}
+	if serviceErr.Kind != ErrorKindCapacity || serviceErr.Hint == "" {
+		t.Fatalf("Should be a capacity error with hint: %+v", serviceErr)
+	}
+	if ClassifyError(serviceErr) != serviceErr {
+		t.Fatal("Classified error shouldn't be wrapped twice")
+	}
+
+	wrapped := fmt.Errorf("Failed to create instance: %w", bceErr)
+	if ClassifyError(wrapped) != wrapped {
+		t.Fatal("Wrapped error should be returned as is")
+	}
+	if kind := ErrorKindOf(wrapped); kind != ErrorKindCapacity {
+		t.Fatalf("Wrapped error should be classified as capacity, got %s", kind)
+	}
+}
@@ -14,10 +14,23 @@ type BaiduCloudImageConfig struct {
 	// The name you want to create your customize image,
 	// it supports upper and lower case letters, numbers, Chinese
 	// and -_/. special characters,
-	// which must start with a letter and be 1-65 in length
+	// which must start with a letter and be 1-65 in length.
+	// It can be omitted if `skip_create_image` is true
 	ImageName string `mapstructure:"image_name" required:"true"`
+	// Run the build through provisioning without creating, copying or
+	// sharing the image, which is useful to iterate on provisioning
+	// scripts. The instance is still deleted after the build, so the
+	// artifact only reports that the provisioning succeeded
+	SkipCreateImage bool `mapstructure:"skip_create_image" required:"false"`
 	// Copy the custom image created by build steps to destination regions
 	DestinationRegions []string `mapstructure:"image_copy_regions" required:"false"`
+	// The id of the KMS key to encrypt the custom image and its snapshots
+	ImageEncryptKeyId string `mapstructure:"image_encrypt_key_id" required:"false"`
+	// A map of destination regions to the ids of KMS keys, which are used
+	// to encrypt the images copied to the regions. KMS keys belong to
+	// a region, so every region of `image_copy_regions` except the source
+	// region must have a key if `image_encrypt_key_id` is set
+	ImageCopyEncryptKeyIds map[string]string `mapstructure:"image_copy_encrypt_key_ids" required:"false"`
 	// Account names of baiducloud to share images to, which
 	// must be a baidu account
 	ImageShareAccounts []string `mapstructure:"image_share_accounts" required:"false"`
@@ -34,7 +47,9 @@ func (c *BaiduCloudImageConfig) Prepare(ctx *interpolate.Context) []error {
 	var errs []error
 
 	if c.ImageName == "" {
-		errs = append(errs, errors.New("image_name must be specified"))
+		if !c.SkipCreateImage {
+			errs = append(errs, errors.New("image_name must be specified"))
+		}
 	} else if len(c.ImageName) > 65 {
 		errs = append(errs, fmt.Errorf("image_name must less than or equal to 65 letters"))
 	} else {
@@ -72,6 +87,12 @@ func (c *BaiduCloudImageConfig) Prepare(ctx *interpolate.Context) []error {
 		c.DestinationRegions = regions
 	}
 
+	for region := range c.ImageCopyEncryptKeyIds {
+		if !containsString(c.DestinationRegions, region) {
+			errs = append(errs, fmt.Errorf("the region(%s) of 'image_copy_encrypt_key_ids' isn't in 'image_copy_regions'", region))
+		}
+	}
+
 	if len(errs) > 0 {
 		return errs
 	}
@@ -49,3 +49,32 @@ func TestImageConfigPrepare_Regions(t *testing.T) {
 		t.Fatalf("Shouldn't raise error: %s", errs)
 	}
 }
+
+func TestImageConfigPrepare_EncryptKeyIds(t *testing.T) {
+	c := getTestImageConfig()
+
+	c.DestinationRegions = []string{"bj", "gz"}
+	c.ImageCopyEncryptKeyIds = map[string]string{"bj": "key-bj"}
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+
+	c.ImageCopyEncryptKeyIds["su"] = "key-su"
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+}
+
+func TestImageConfigPrepare_SkipCreateImage(t *testing.T) {
+	c := getTestImageConfig()
+	c.ImageName = ""
+	c.SkipCreateImage = true
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+
+	c.ImageName = "1-bad-name"
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+}
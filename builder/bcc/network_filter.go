@@ -0,0 +1,191 @@
+//go:generate packer-sdc struct-markdown
+
+package bcc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/baidubce/bce-sdk-go/model"
+	"github.com/baidubce/bce-sdk-go/services/bcc"
+	"github.com/baidubce/bce-sdk-go/services/vpc"
+)
+
+// NetworkFilter looks up an existing vpc, subnet or security group, instead
+// of setting its id. All the set conditions must match, and the tags match
+// if the resource has all of them.
+type NetworkFilter struct {
+	// The name of the resource.
+	Name string `mapstructure:"name" required:"false"`
+	// The tags the resource must have.
+	Tags map[string]string `mapstructure:"tags" required:"false"`
+	// The cidr block of the vpc or subnet.
+	Cidr string `mapstructure:"cidr" required:"false"`
+	// The zone of the subnet.
+	Zone string `mapstructure:"zone" required:"false"`
+	// If more than one subnet matches, use the one with the most free ips,
+	// instead of failing. Only the subnets in `zone` or `zones` of the
+	// builder are considered, if `zone` is set.
+	MostFreeIps bool `mapstructure:"most_free_ips" required:"false"`
+}
+
+// Empty reports whether no condition is set
+func (f *NetworkFilter) Empty() bool {
+	return f.Name == "" && len(f.Tags) == 0 && f.Cidr == "" && f.Zone == "" && !f.MostFreeIps
+}
+
+func (f *NetworkFilter) match(name, cidr, zone string, tags []model.TagModel) bool {
+	if f.Name != "" && f.Name != name {
+		return false
+	}
+	if f.Cidr != "" && f.Cidr != cidr {
+		return false
+	}
+	if f.Zone != "" && f.Zone != zone {
+		return false
+	}
+	for key, value := range f.Tags {
+		found := false
+		for _, tag := range tags {
+			if tag.TagKey == key && tag.TagValue == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// validate checks that only the conditions supported by the resource are set
+func (f *NetworkFilter) validate(name string, zone, mostFreeIps, cidr bool) []error {
+	var errs []error
+	if f.Zone != "" && !zone {
+		errs = append(errs, fmt.Errorf("'zone' isn't supported by '%s'", name))
+	}
+	if f.MostFreeIps && !mostFreeIps {
+		errs = append(errs, fmt.Errorf("'most_free_ips' isn't supported by '%s'", name))
+	}
+	if f.Cidr != "" && !cidr {
+		errs = append(errs, fmt.Errorf("'cidr' isn't supported by '%s'", name))
+	}
+	return errs
+}
+
+// ambiguousError describes the resources matched by a filter
+func ambiguousError(kind string, ids []string) error {
+	if len(ids) == 0 {
+		return fmt.Errorf("no %s matches the filter", kind)
+	}
+	return fmt.Errorf("%d %ss match the filter: %s, please narrow it down", len(ids), kind, strings.Join(ids, ", "))
+}
+
+// findVpc returns the id of the only vpc matching the filter
+func findVpc(ctx context.Context, client *vpc.Client, f *NetworkFilter) (string, error) {
+	var ids []string
+	args := &vpc.ListVPCArgs{MaxKeys: 1000}
+	for {
+		var listResult *vpc.ListVPCResult
+		err := Retry(ctx, func(ctx context.Context) error {
+			var e error
+			listResult, e = client.ListVPC(args)
+			return e
+		})
+		if err != nil {
+			return "", err
+		}
+		for _, v := range listResult.VPCs {
+			if f.match(v.Name, v.Cidr, "", v.Tags) {
+				ids = append(ids, v.VPCID)
+			}
+		}
+		if !listResult.IsTruncated {
+			break
+		}
+		args.Marker = listResult.NextMarker
+	}
+
+	if len(ids) != 1 {
+		return "", ambiguousError("vpc", ids)
+	}
+	return ids[0], nil
+}
+
+// findSubnet returns the id of the only subnet in the vpc matching the
+// filter, or the one with the most free ips if `most_free_ips` is set. The
+// subnets outside the zones are ignored, unless zones is empty.
+func findSubnet(ctx context.Context, client *vpc.Client, vpcId string, f *NetworkFilter, zones []string) (string, error) {
+	var subnets []vpc.Subnet
+	args := &vpc.ListSubnetArgs{
+		VpcId:    vpcId,
+		ZoneName: f.Zone,
+		MaxKeys:  1000,
+	}
+	for {
+		var listResult *vpc.ListSubnetResult
+		err := Retry(ctx, func(ctx context.Context) error {
+			var e error
+			listResult, e = client.ListSubnets(args)
+			return e
+		})
+		if err != nil {
+			return "", err
+		}
+		for _, subnet := range listResult.Subnets {
+			if len(zones) > 0 && !containsString(zones, subnet.ZoneName) {
+				continue
+			}
+			if f.match(subnet.Name, subnet.Cidr, subnet.ZoneName, subnet.Tags) {
+				subnets = append(subnets, subnet)
+			}
+		}
+		if !listResult.IsTruncated {
+			break
+		}
+		args.Marker = listResult.NextMarker
+	}
+
+	if len(subnets) == 0 {
+		return "", ambiguousError("subnet", nil)
+	}
+	if f.MostFreeIps {
+		best := subnets[0]
+		for _, subnet := range subnets[1:] {
+			if subnet.AvailableIp > best.AvailableIp {
+				best = subnet
+			}
+		}
+		return best.SubnetId, nil
+	}
+	if len(subnets) > 1 {
+		var ids []string
+		for _, subnet := range subnets {
+			ids = append(ids, subnet.SubnetId)
+		}
+		return "", ambiguousError("subnet", ids)
+	}
+	return subnets[0].SubnetId, nil
+}
+
+// findSecurityGroup returns the id of the only security group in the vpc
+// matching the filter
+func findSecurityGroup(ctx context.Context, client *bcc.Client, vpcId string, f *NetworkFilter) (string, error) {
+	securityGroups, err := listSecurityGroups(ctx, client, vpcId)
+	if err != nil {
+		return "", err
+	}
+
+	var ids []string
+	for _, securityGroup := range securityGroups {
+		if f.match(securityGroup.Name, "", "", securityGroup.Tags) {
+			ids = append(ids, securityGroup.Id)
+		}
+	}
+	if len(ids) != 1 {
+		return "", ambiguousError("security group", ids)
+	}
+	return ids[0], nil
+}
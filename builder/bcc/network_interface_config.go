@@ -0,0 +1,30 @@
+//go:generate packer-sdc struct-markdown
+
+package bcc
+
+import (
+	"fmt"
+)
+
+// NetworkInterfaceConfig creates an elastic network interface, which is
+// attached to the build instance after it's launched, and detached and
+// deleted before the image is created, so the image doesn't depend on it.
+type NetworkInterfaceConfig struct {
+	// The subnet of the network interface, which must be in the vpc and zone
+	// of the build instance. Defaults to the subnet of the build instance.
+	SubnetId string `mapstructure:"subnet_id" required:"false"`
+	// The security groups of the network interface. Defaults to the security
+	// groups of the build instance.
+	SecurityGroupIds []string `mapstructure:"security_group_ids" required:"false"`
+	// The number of secondary private ips assigned to the network interface,
+	// besides the primary one. Defaults to `0`.
+	SecondaryPrivateIpCount int `mapstructure:"secondary_private_ip_count" required:"false"`
+}
+
+func (c *NetworkInterfaceConfig) Prepare(index int) []error {
+	var errs []error
+	if c.SecondaryPrivateIpCount < 0 {
+		errs = append(errs, fmt.Errorf("'secondary_private_ip_count' of 'network_interfaces[%d]' can't be negative", index))
+	}
+	return errs
+}
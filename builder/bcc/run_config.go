@@ -5,7 +5,9 @@ package bcc
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"time"
 
 	"github.com/hashicorp/packer-plugin-sdk/communicator"
 	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
@@ -23,27 +25,79 @@ import (
 // 	SnapShotId string `mapstructure:"snapshot_id"`
 // }
 
+const (
+	SpotStrategyNone        = "none"
+	SpotStrategyMarketPrice = "market_price"
+	SpotStrategyCustomPrice = "custom_price"
+)
+
+// the values of `ssh_interface`
+const (
+	sshInterfacePublicIp  = "public_ip"
+	sshInterfacePrivateIp = "private_ip"
+	sshInterfaceIpv6      = "ipv6"
+)
+
 type BaiduCloudRunConfig struct {
 	// Whether allocate public ip(eip) to your instance.
 	// Default value is false. If you set this field `true`,
 	// please set the `network_capacity_in_mbps` field to allocate
 	// public network bandwith
 	AssociatePublicIpAddress bool `mapstructure:"associate_public_ip_address" required:"false"`
+	// Create a temporary nat gateway with an eip, and route the traffic of
+	// the subnet to the internet through it, so that the instance without
+	// public ip can download packages while provisioning. The route rule,
+	// snat rule, nat gateway and eip are deleted after the build. It can't be
+	// used along with `use_default_network` or `associate_public_ip_address`
+	TemporaryNatGateway bool `mapstructure:"temporary_nat_gateway" required:"false"`
+	// The bandwidth of the eip of the temporary nat gateway, which is
+	// charged by traffic. Defaults to `100`
+	TemporaryNatGatewayBandwidthInMbps int `mapstructure:"temporary_nat_gateway_bandwidth_in_mbps" required:"false"`
+	// Launch a temporary bastion instance with an eip to connect to the
+	// build instance, which has no public ip. The `ssh_bastion_*` fields are
+	// set automatically. See [Temporary Bastion](#temporary-bastion).
+	TemporaryBastion *TemporaryBastionConfig `mapstructure:"temporary_bastion" required:"false"`
 	// Use default vpc, subnet, securitygroup, if the field is set `true`.
 	// if the field is set `false` or not set, the build process will use
 	// a vpc, subnet, securitygroup that you offer or create a temporary vpc,
 	// subnet, securitygroup, which will be deleted after building.
 	UseDefaultNetwork bool `mapstructure:"use_default_network" required:"false"`
+	// The id of an existing instance to rebuild from `source_image_id` with
+	// the keypair or password of the build, instead of launching a new
+	// instance. The instance keeps its spec and network, so no vpc, subnet or
+	// security group is configured, and it's stopped instead of deleted
+	// after the build. It's stopped before rebuilding if it's running.
+	ReuseInstanceId string `mapstructure:"reuse_instance_id" required:"false"`
+	// The id of an existing instance to capture the image from, instead of
+	// launching a new instance from `source_image_id`. No network, keypair
+	// or instance is created, and the instance is never deleted. The
+	// provisioners run against it with the configured ssh or winrm
+	// credentials, or through the cloud assistant provisioner if the
+	// communicator is `none`. A stopped instance is started, unless the
+	// communicator is `none`, and stopped again after the build. The
+	// instance is kept as it is, so `windows_sysprep` and
+	// `ssh_clear_authorized_keys` can't be used with it.
+	SourceInstanceId string `mapstructure:"source_instance_id" required:"false"`
+	// Stop the instance of `source_instance_id` before capturing the image,
+	// so that the file systems are consistent.
+	StopSourceInstance bool `mapstructure:"stop_source_instance" required:"false"`
 	// Type of the instance. For values, see the section [区域机型以及可选配置] of
-	// website https://cloud.baidu.com/doc/BCC/s/6jwvyo0q2
+	// website https://cloud.baidu.com/doc/BCC/s/6jwvyo0q2.
+	// It can be omitted if `instance_specs`, `reuse_instance_id` or
+	// `source_instance_id` is set
 	InstanceSpec string `mapstructure:"instance_spec" required:"true"`
+	// A prioritized list of instance types, which can't be set along with
+	// `instance_spec`. If a type is sold out in the zone, the next one will
+	// be tried. The type which is finally used is recorded in the artifact
+	InstanceSpecs []string `mapstructure:"instance_specs" required:"false"`
 	// The name of instance which will be launch. Usually it will be deleted
 	// after build, cancellation or error
 	InstanceName string `mapstructure:"instance_name" required:"false"`
 	// The description of instance
 	Description string `mapstructure:"description"`
 	// The base image id of Image you want to create
-	// your customized image from
+	// your customized image from.
+	// It can be omitted if `source_instance_id` is set
 	SourceImageId string `mapstructure:"source_image_id" required:"true"`
 	// ID of the security group to which a newly
 	// created instance belongs. Mutual access is allowed between instances in one
@@ -54,6 +108,22 @@ type BaiduCloudRunConfig struct {
 	SecurityGroupId string `mapstructure:"security_group_id" required:"false"`
 	// The security group name
 	SecurityGroupName string `mapstructure:"security_group_name" required:"false"`
+	// Look up the security group in the vpc by `name` and `tags`, instead of
+	// setting `security_group_id`. The build fails if not exactly one
+	// security group matches.
+	SecurityGroupFilter NetworkFilter `mapstructure:"security_group_filter" required:"false"`
+	// The ids of the existing security groups in the vpc to attach to the
+	// instance, along with `security_group_id`, or the temporary security
+	// group if it isn't set.
+	SecurityGroupIds []string `mapstructure:"security_group_ids" required:"false"`
+	// The ids of the enterprise security groups to attach to the instance.
+	// Enterprise security groups can't be used along with normal security
+	// groups, so no temporary security group is created.
+	EnterpriseSecurityGroupIds []string `mapstructure:"enterprise_security_group_ids" required:"false"`
+	// The secondary network interfaces to attach to the build instance, in
+	// order, such as for images that configure multi-NIC routing while
+	// provisioning. See [Network Interfaces](#network-interfaces).
+	NetworkInterfaces []NetworkInterfaceConfig `mapstructure:"network_interfaces" required:"false"`
 	// Internet charge type, there are two type: `BANDWIDTH_POSTPAID_BY_HOUR` and
 	// `TRAFFIC_POSTPAID_BY_HOUR`.
 	// The default type is `BANDWIDTH_POSTPAID_BY_HOUR`
@@ -62,10 +132,36 @@ type BaiduCloudRunConfig struct {
 	EipName string `mapstructure:"eip_name" required:"false"`
 	// Eip network bandwith
 	NetworkCapacityInMbps int `mapstructure:"network_capacity_in_mbps" required:"false"`
+	// The spot strategy of the instance, there are three strategies: `none`,
+	// `market_price` and `custom_price`. The default strategy is `none`, which
+	// means a postpaid instance will be launched. `market_price` bids at the
+	// market price, and `custom_price` bids at `spot_price_limit`
+	SpotStrategy string `mapstructure:"spot_strategy" required:"false"`
+	// The highest price per hour you'd like to pay for the spot instance,
+	// which must be set if `spot_strategy` is `custom_price`
+	SpotPriceLimit float64 `mapstructure:"spot_price_limit" required:"false"`
+	// Launch a postpaid instance instead, if the spot instance can't be
+	// created because of price or stock. The default value is false
+	SpotFallbackToOnDemand bool `mapstructure:"spot_fallback_to_on_demand" required:"false"`
+	// The id of the deployment set to launch the instance in. It's checked
+	// not to be full in the zones the instance may be launched in, before
+	// launching the instance
+	DeploymentSetId string `mapstructure:"deployment_set_id" required:"false"`
+	// Create a temporary deployment set to launch the instance in, which will
+	// be deleted after building. It can't be set along with `deployment_set_id`
+	AutoCreateDeploymentSet bool `mapstructure:"auto_create_deployment_set" required:"false"`
+	// The id of the dedicated host to launch the instance on. The dedicated
+	// host must be in `zone`, or the zone of `subnet_id` if `zone` is omitted.
+	// `zones` or spot instance can't be used with it. The zone of the
+	// dedicated host is checked before launching the instance, since it
+	// needs to query the api
+	DedicatedHostId string `mapstructure:"dedicated_host_id" required:"false"`
 	// RootDiskSizeInGb, if not provided, the default value is 20G
 	RootDiskSizeInGb int `mapstructure:"root_disk_size_in_gb" required:"false"`
 	// RootDiskStorageType, if not provided, the default type is hp1
 	RootDiskStorageType string `mapstructure:"root_disk_storage_type" required:"false"`
+	// The id of the KMS key to encrypt the root disk of the instance
+	RootDiskEncryptKeyId string `mapstructure:"root_disk_encrypt_key_id" required:"false"`
 	// The VPC id for your build process will perform in. If the field
 	// `use_default_network` is used. This field will be invalid
 	VpcId string `mapstructure:"vpc_id" require:"false"`
@@ -73,20 +169,61 @@ type BaiduCloudRunConfig struct {
 	// both are unset, the build process will create a temporary
 	// vpc network with the name of `vpc_name`
 	VpcName string `mapstructure:"vpc_name" require:"false"`
+	// Look up the vpc by `name`, `tags` and `cidr`, instead of setting
+	// `vpc_id`. The build fails if not exactly one vpc matches.
+	VpcFilter NetworkFilter `mapstructure:"vpc_filter" required:"false"`
 	// Cidr block for VPC. If the temporary vpc network is created, the
-	// `vpc_cidr_block` will be used to set cidr for vpc
+	// `vpc_cidr_block` will be used to set cidr for vpc. Defaults to
+	// `192.168.0.0/16`, or the first private /16 block which doesn't overlap
+	// `avoid_cidrs`
 	CidrBlock string `mapstructure:"vpc_cidr_block" required:"false"`
-	// The id of subnet where the bcc instance will be launched on
+	// Enable ipv6 for the temporary vpc and subnet, and assign an ipv6
+	// address to the instance. The existing vpc and subnet must have ipv6
+	// enabled already. Set `ssh_interface` to `ipv6` to connect to the
+	// instance by its ipv6 address.
+	EnableIpv6 bool `mapstructure:"enable_ipv6" required:"false"`
+	// The address of the instance to connect to, one of `public_ip`,
+	// `private_ip` and `ipv6`. Defaults to `public_ip` if
+	// `associate_public_ip_address` is true, otherwise `private_ip`.
+	SSHInterface string `mapstructure:"ssh_interface" required:"false"`
+	// The cidr blocks which the temporary vpc and subnet must not overlap,
+	// such as the networks peered with the vpc.
+	AvoidCidrs []string `mapstructure:"avoid_cidrs" required:"false"`
+	// The id of subnet where the bcc instance will be launched on. It must
+	// belong to `vpc_id`, be in `zone` (or one of `zones`) and have free ips
 	SubnetId string `mapstructure:"subnet_id" required:"false"`
+	// Look up the subnet in the vpc by `name`, `tags`, `cidr` and `zone`,
+	// instead of setting `subnet_id`. The build fails if not exactly one
+	// subnet matches, unless `most_free_ips` is set.
+	SubnetFilter NetworkFilter `mapstructure:"subnet_filter" required:"false"`
 	// The cidr block of subnet which will be created
-	// if subnet id is not specified
+	// if subnet id is not specified. If it's `auto`, the first block of
+	// `subnet_cidr_prefix_length` in the vpc, which overlaps neither the
+	// existing subnets nor `avoid_cidrs`, is used
 	SubnetCidrBlock string `mapstructure:"subnet_cidr_block" required:"false"`
+	// The prefix length of the subnet cidr block picked by
+	// `subnet_cidr_block = "auto"`. Defaults to `24`
+	SubnetCidrPrefixLength int `mapstructure:"subnet_cidr_prefix_length" required:"false"`
 	// The subnet name which will be created if subnet id is not specified
 	SubnetName string `mapstructure:"subnet_name" required:"false"`
 	// Keypair id for ssh. baiducloud use keypair id as unique identification.
-	// And maybe there more than one keypairs have the same name. So please
-	// use `keypair_id` instead of `ssh_keypair_name`
+	// And maybe there more than one keypairs have the same name. If
+	// `ssh_keypair_name` is set instead, it's looked up by name, and the build
+	// fails if there isn't exactly one keypair with the name. `keypair_id`
+	// takes precedence if both are set. Either way, the private key must be
+	// provided by `ssh_private_key_file` or `ssh_agent_auth`
 	KeypairId string `mapstructure:"keypair_id" required:"false"`
+	// Generate the temporary key pair locally and import its public key as
+	// the temporary keypair, instead of creating the keypair by BCC. So the
+	// private key never leaves the build host. The type and bits of the key
+	// are set by `temporary_key_pair_type` and `temporary_key_pair_bits`, and
+	// only `rsa` and `ed25519` are supported
+	ImportTemporaryKeyPair bool `mapstructure:"import_temporary_key_pair" required:"false"`
+	// Path to a public key file to import as the temporary keypair. The
+	// private key must be provided by `ssh_private_key_file` or the ssh agent
+	// with `ssh_agent_auth`. The comment of the key is replaced with the
+	// keypair name, so that `ssh_clear_authorized_keys` can remove it
+	SSHPublicKeyFile string `mapstructure:"ssh_public_key_file" required:"false"`
 	// Add one or more data disks to the instance before creating the image.
 	// The data disks allow for the following argument:
 	// -  `storage_type` - Type of the data disk.
@@ -99,11 +236,69 @@ type BaiduCloudRunConfig struct {
 	// User data to apply when launching the instance.
 	// It is often more convenient to use user_data_file, instead.
 	// Packer will not automatically wait for a user script to finish before
-	// shutting down the instance this must be handled in a provisioner.
+	// provisioning unless `wait_for_user_data` is set.
 	UserData string `mapstructure:"user_data" required:"false"`
 	// Path to a file that will be used for the user
 	// data when launching the instance.
 	UserDataFile string `mapstructure:"user_data_file" required:"false"`
+	// Paths to the files which are assembled into a MIME multi-part document
+	// as the user data, which can't be set along with `user_data` or
+	// `user_data_file`. The content type of each part is detected by its
+	// first line, such as `#cloud-config` or `#!`. The files are interpolated
+	// with `{{ .BuildName }}`, `{{ .Region }}`, `{{ .Zone }}`,
+	// `{{ .SSHPublicKey }}` and the generated data of the builder
+	UserDataFiles []string `mapstructure:"user_data_files" required:"false"`
+	// Wait for cloud-init, or cloudbase-init on windows, to finish running the
+	// user data before provisioning. The status is polled over the
+	// communicator, and the build fails along with the tail of the log if the
+	// user data fails or times out. It can't be used with the `none`
+	// communicator
+	WaitForUserData bool `mapstructure:"wait_for_user_data" required:"false"`
+	// The timeout of waiting for the user data, such as `30s` or `10m`. The
+	// default value is `30m`
+	UserDataTimeout time.Duration `mapstructure:"user_data_timeout" required:"false"`
+
+	// Generate a random password which meets the complexity requirements of
+	// BCC as the `ssh_password`, for the source images which don't support
+	// keypair. The password is also exposed to provisioners as the generated
+	// data `TemporaryPassword`. It can't be set along with `ssh_password` or
+	// `keypair_id`
+	SSHTemporaryPassword bool `mapstructure:"ssh_temporary_password" required:"false"`
+	// Reset the admin password to another random one before creating the
+	// image, so that the temporary password can't be used to login the
+	// instances launched from the image. It can only be used along with
+	// `ssh_temporary_password`
+	ResetTemporaryPassword bool `mapstructure:"reset_temporary_password" required:"false"`
+	// Generalize the windows instance with sysprep before creating the image.
+	// The instance will be shut down by sysprep, and the image is created once
+	// the instance stops. It can only be used with the `winrm` communicator
+	WindowsSysprep bool `mapstructure:"windows_sysprep" required:"false"`
+
+	// Skip checking the instance quota before building
+	SkipInstanceQuotaCheck bool `mapstructure:"skip_instance_quota_check" required:"false"`
+	// Skip checking the vpc quota before building, which is only checked if
+	// a temporary vpc will be created
+	SkipVpcQuotaCheck bool `mapstructure:"skip_vpc_quota_check" required:"false"`
+	// Skip checking the subnet quota before building, which is only checked if
+	// a temporary subnet will be created
+	SkipSubnetQuotaCheck bool `mapstructure:"skip_subnet_quota_check" required:"false"`
+	// Skip checking the security group quota before building, which is only
+	// checked if a temporary security group will be created
+	SkipSecurityGroupQuotaCheck bool `mapstructure:"skip_security_group_quota_check" required:"false"`
+	// Skip checking the eip quota before building, which is only checked if
+	// `associate_public_ip_address` is true
+	SkipEipQuotaCheck bool `mapstructure:"skip_eip_quota_check" required:"false"`
+	// Skip checking the custom image quota of the region and the copy regions
+	// before building
+	SkipImageQuotaCheck bool `mapstructure:"skip_image_quota_check" required:"false"`
+	// Check the cash balance of the account before building, the build fails
+	// if the account is in arrears. The default value is false
+	CheckBalance bool `mapstructure:"check_balance" required:"false"`
+	// Run the read-only checks against the account, such as the source image,
+	// the availability of instance specs, the network, keypair, image names
+	// and quotas, and print the plan of the resources which would be created
+	// and deleted, without creating anything. No artifact is produced
+	DryRun bool `mapstructure:"dry_run" required:"false"`
 
 	// Communicator settings
 	Comm communicator.Config `mapstructure:",squash"`
@@ -111,91 +306,302 @@ type BaiduCloudRunConfig struct {
 	// the BCC instance created through private ip instead of allocating  an
 	// EIP. The default value is false.
 	// SSHPrivateIp bool `mapstructure:"ssh_private_ip" required:"false"`
+
+	// packerId names the temporary resources of the build
+	packerId string
 }
 
 func (c *BaiduCloudRunConfig) Prepare(ctx *interpolate.Context) []error {
 	packerId := fmt.Sprintf("packer_%s", uuid.TimeOrderedUUID()[:8])
+	c.packerId = packerId
 
-	if c.KeypairId == "" && c.Comm.SSHKeyPairName == "" && c.Comm.SSHTemporaryKeyPairName == "" &&
-		c.Comm.SSHPrivateKeyFile == "" && c.Comm.SSHPassword == "" && c.Comm.WinRMPassword == "" {
-		c.Comm.SSHTemporaryKeyPairName = packerId
+	var errs []error
+	if c.isWindows() {
+		// windows instances don't support keypair, the admin password
+		// is used by winrm instead
+		if c.Comm.WinRMUser == "" {
+			c.Comm.WinRMUser = "Administrator"
+		}
+		if c.Comm.WinRMPassword == "" && c.SourceInstanceId != "" {
+			errs = append(errs, errors.New("'winrm_password' must be specified, since the 'source_instance_id' has been set"))
+		} else if c.Comm.WinRMPassword == "" {
+			password, err := generatePassword()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to generate winrm password: %s", err))
+			}
+			c.Comm.WinRMPassword = password
+		}
+		if c.KeypairId != "" {
+			errs = append(errs, errors.New("'keypair_id' can't be used along with the winrm communicator"))
+		}
+		if c.SSHTemporaryPassword || c.ImportTemporaryKeyPair || c.SSHPublicKeyFile != "" {
+			errs = append(errs, errors.New("'ssh_temporary_password', 'import_temporary_key_pair' or 'ssh_public_key_file' "+
+				"can't be used along with the winrm communicator"))
+		}
+	} else {
+		// 'keypair_id' takes precedence over 'ssh_keypair_name'
+		if c.Comm.SSHKeyPairName != "" && c.KeypairId == "" &&
+			c.Comm.SSHPrivateKeyFile == "" && !c.Comm.SSHAgentAuth {
+			errs = append(errs, errors.New("'ssh_private_key_file' or 'ssh_agent_auth' must be specified, "+
+				"since the 'ssh_keypair_name' has been set"))
+		}
+		if c.SSHPublicKeyFile != "" {
+			// the public key file is always imported as a temporary keypair
+			if c.Comm.SSHTemporaryKeyPairName == "" {
+				c.Comm.SSHTemporaryKeyPairName = packerId
+			}
+			if _, err := os.Stat(c.SSHPublicKeyFile); err != nil {
+				errs = append(errs, errors.New("the file path of 'ssh_public_key_file' doesn't exist"))
+			}
+			if c.Comm.SSHPrivateKeyFile == "" && !c.Comm.SSHAgentAuth {
+				errs = append(errs, errors.New("'ssh_private_key_file' or 'ssh_agent_auth' must be specified, "+
+					"since the 'ssh_public_key_file' has been set"))
+			}
+			if c.KeypairId != "" || c.Comm.SSHKeyPairName != "" || c.ImportTemporaryKeyPair {
+				errs = append(errs, errors.New("'ssh_public_key_file' can't be used along with 'keypair_id', "+
+					"'ssh_keypair_name' or 'import_temporary_key_pair'"))
+			}
+		}
+		if c.ImportTemporaryKeyPair {
+			if c.KeypairId != "" || c.Comm.SSHKeyPairName != "" || c.Comm.SSHPrivateKeyFile != "" ||
+				c.Comm.SSHAgentAuth || c.SSHTemporaryPassword {
+				errs = append(errs, errors.New("'import_temporary_key_pair' can't be used along with 'keypair_id', "+
+					"'ssh_keypair_name', 'ssh_private_key_file', 'ssh_agent_auth' or 'ssh_temporary_password'"))
+			}
+			switch c.Comm.SSHTemporaryKeyPairType {
+			case "", "rsa", "ed25519":
+			default:
+				errs = append(errs, fmt.Errorf("'temporary_key_pair_type' %s can't be imported, only rsa and ed25519 are supported",
+					c.Comm.SSHTemporaryKeyPairType))
+			}
+		}
+		if c.SSHTemporaryPassword {
+			if c.Comm.SSHPassword != "" || c.KeypairId != "" {
+				errs = append(errs, errors.New("'ssh_temporary_password' can't be used along with 'ssh_password' or 'keypair_id'"))
+			} else {
+				password, err := generatePassword()
+				if err != nil {
+					errs = append(errs, fmt.Errorf("failed to generate ssh password: %s", err))
+				}
+				c.Comm.SSHPassword = password
+			}
+		}
+		// no keypair is needed without communicator, the commands can be
+		// run by the cloud assistant provisioner instead. The keypair of an
+		// existing instance can't be changed.
+		if c.KeypairId == "" && c.Comm.SSHKeyPairName == "" && c.Comm.SSHTemporaryKeyPairName == "" &&
+			c.Comm.SSHPrivateKeyFile == "" && c.Comm.SSHPassword == "" && c.Comm.Type != "none" &&
+			c.SourceInstanceId == "" {
+			c.Comm.SSHTemporaryKeyPairName = packerId
+		}
+		if c.WindowsSysprep {
+			errs = append(errs, errors.New("'windows_sysprep' can only be used along with the winrm communicator"))
+		}
+	}
+
+	if c.ResetTemporaryPassword && !c.SSHTemporaryPassword {
+		errs = append(errs, errors.New("'reset_temporary_password' can only be used along with 'ssh_temporary_password'"))
 	}
 
 	if c.RunTags == nil {
 		c.RunTags = make(map[string]string)
 	}
 
-	errs := c.Comm.Prepare(ctx)
+	errs = append(errs, c.Comm.Prepare(ctx)...)
 
-	if c.SourceImageId == "" {
+	if c.SourceInstanceId != "" {
+		// the image is captured from the instance as it is
+		sourceOptions := []configOption{
+			{"'source_image_id'", c.SourceImageId != ""},
+			{"'reuse_instance_id'", c.ReuseInstanceId != ""},
+			{"'keypair_id' or 'ssh_keypair_name'", c.KeypairId != "" || c.Comm.SSHKeyPairName != ""},
+			{"'ssh_temporary_password', 'import_temporary_key_pair' or 'ssh_public_key_file'",
+				c.SSHTemporaryPassword || c.ImportTemporaryKeyPair || c.SSHPublicKeyFile != ""},
+			{"'wait_for_user_data'", c.WaitForUserData},
+			// the source instance must be kept as it is
+			{"'windows_sysprep'", c.WindowsSysprep},
+			{"'ssh_clear_authorized_keys'", c.Comm.SSHClearAuthorizedKeys},
+		}
+		for _, option := range sourceOptions {
+			if option.set {
+				errs = append(errs, fmt.Errorf("%s can't be used along with 'source_instance_id'", option.name))
+			}
+		}
+		if c.Comm.Type == "ssh" && c.Comm.SSHPassword == "" && c.Comm.SSHPrivateKeyFile == "" && !c.Comm.SSHAgentAuth {
+			errs = append(errs, errors.New("one of 'ssh_password', 'ssh_private_key_file' or 'ssh_agent_auth' must be specified, since the 'source_instance_id' has been set"))
+		}
+	} else if c.SourceImageId == "" {
 		errs = append(errs, errors.New("'source_image_id' must be specified"))
 	}
+	if c.StopSourceInstance && c.SourceInstanceId == "" {
+		errs = append(errs, errors.New("'stop_source_instance' can only be used along with 'source_instance_id'"))
+	}
 
-	if c.InstanceSpec == "" {
-		errs = append(errs, errors.New("'instance_spec' must be specified"))
+	if c.InstanceSpec == "" && len(c.InstanceSpecs) == 0 && c.ReuseInstanceId == "" && c.SourceInstanceId == "" {
+		errs = append(errs, errors.New("'instance_spec' or 'instance_specs' must be specified"))
+	} else if c.InstanceSpec != "" && len(c.InstanceSpecs) > 0 {
+		errs = append(errs, errors.New("only one of 'instance_spec' or 'instance_specs' can be specified"))
+	}
+	for _, spec := range c.InstanceSpecs {
+		if spec == "" {
+			errs = append(errs, errors.New("'instance_specs' shouldn't contain empty value"))
+			break
+		}
 	}
 
 	if c.UserData != "" && c.UserDataFile != "" {
 		errs = append(errs, errors.New("only one of 'user_data' or 'user_data_file' can be specified"))
 	} else if c.UserDataFile != "" {
-		if _, err := os.Stat(c.UserDataFile); err != nil {
+		if data, err := ioutil.ReadFile(c.UserDataFile); err != nil {
 			errs = append(errs, errors.New("the file path of 'user_data_file' doesn't exist"))
+		} else if err := checkUserDataSize(string(data)); err != nil {
+			errs = append(errs, err)
+		}
+	} else if c.UserData != "" {
+		if err := checkUserDataSize(c.UserData); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(c.UserDataFiles) > 0 {
+		if c.UserData != "" || c.UserDataFile != "" {
+			errs = append(errs, errors.New("'user_data_files' can't be used along with 'user_data' or 'user_data_file'"))
+		}
+		// the files are interpolated while launching the instance, which
+		// may change the size a little
+		if userData, err := assembleUserData(c.UserDataFiles, nil); err != nil {
+			errs = append(errs, fmt.Errorf("failed to assemble 'user_data_files': %s", err))
+		} else if err := checkUserDataSize(userData); err != nil {
+			errs = append(errs, err)
 		}
 	}
 
-	if c.UseDefaultNetwork {
+	if c.UserDataTimeout == 0 {
+		c.UserDataTimeout = 30 * time.Minute
+	}
+	if c.UserDataTimeout < 0 {
+		errs = append(errs, errors.New("'user_data_timeout' must be positive"))
+	}
+	if c.WaitForUserData && c.Comm.Type == "none" {
+		errs = append(errs, errors.New("'wait_for_user_data' can't be used since the communicator is none"))
+	}
+
+	existingVpc := c.VpcId != "" || !c.VpcFilter.Empty()
+	existingSubnet := c.SubnetId != "" || !c.SubnetFilter.Empty()
+	existingSecurityGroup := c.SecurityGroupId != "" || !c.SecurityGroupFilter.Empty()
+	enterpriseSecurityGroup := len(c.EnterpriseSecurityGroupIds) > 0
+	if c.ReuseInstanceId != "" || c.SourceInstanceId != "" {
+		// the existing instance keeps its own spec, network and placement,
+		// and the user data can't be changed by rebuilding
+		mode := "reuse_instance_id"
+		if c.SourceInstanceId != "" {
+			mode = "source_instance_id"
+		}
+		for _, option := range c.launchOptions() {
+			if option.set {
+				errs = append(errs, fmt.Errorf("%s can't be used along with '%s'", option.name, mode))
+			}
+		}
+	} else if c.UseDefaultNetwork {
 		// If using default network, there is no need to provide network info
-		if c.VpcId != "" || c.VpcName != "" || c.CidrBlock != "" || c.SubnetId != "" ||
-			c.SubnetCidrBlock != "" || c.SecurityGroupId != "" || c.SecurityGroupName != "" {
+		if existingVpc || c.VpcName != "" || c.CidrBlock != "" || existingSubnet ||
+			c.SubnetCidrBlock != "" || existingSecurityGroup || c.SecurityGroupName != "" ||
+			len(c.SecurityGroupIds) > 0 || enterpriseSecurityGroup || len(c.AvoidCidrs) > 0 {
 			errs = append(errs, errors.New("there is no need to provide vpc info, subnet info or "+
 				"security group info, since the field 'use_default_network' has been set true"))
 		}
 	} else {
+		if c.VpcId != "" && !c.VpcFilter.Empty() {
+			errs = append(errs, errors.New("only one of 'vpc_id' and 'vpc_filter' can be set"))
+		}
+		if c.SubnetId != "" && !c.SubnetFilter.Empty() {
+			errs = append(errs, errors.New("only one of 'subnet_id' and 'subnet_filter' can be set"))
+		}
+		if c.SecurityGroupId != "" && !c.SecurityGroupFilter.Empty() {
+			errs = append(errs, errors.New("only one of 'security_group_id' and 'security_group_filter' can be set"))
+		}
+		if enterpriseSecurityGroup && (existingSecurityGroup || c.SecurityGroupName != "" || len(c.SecurityGroupIds) > 0) {
+			errs = append(errs, errors.New("'enterprise_security_group_ids' can't be used along with "+
+				"'security_group_id', 'security_group_filter', 'security_group_name' or 'security_group_ids'"))
+		}
+		avoidCidrs, err := parseCidrs(c.AvoidCidrs)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid 'avoid_cidrs': %s", err))
+		}
+		if c.SubnetCidrPrefixLength == 0 {
+			c.SubnetCidrPrefixLength = 24
+		}
+		if c.SubnetCidrPrefixLength < 8 || c.SubnetCidrPrefixLength > 29 {
+			errs = append(errs, errors.New("'subnet_cidr_prefix_length' must be between 8 and 29"))
+		}
+		if c.SubnetCidrBlock != "" && c.SubnetCidrBlock != cidrAuto {
+			if subnetCidrs, err := parseCidrs([]string{c.SubnetCidrBlock}); err != nil {
+				errs = append(errs, fmt.Errorf("invalid 'subnet_cidr_block': %s", err))
+			} else if n := overlapsAny(subnetCidrs[0], avoidCidrs); n != nil {
+				errs = append(errs, fmt.Errorf("'subnet_cidr_block' overlaps %s of 'avoid_cidrs'", n))
+			}
+		}
+		errs = append(errs, c.VpcFilter.validate("vpc_filter", false, false, true)...)
+		errs = append(errs, c.SubnetFilter.validate("subnet_filter", true, true, true)...)
+		errs = append(errs, c.SecurityGroupFilter.validate("security_group_filter", false, false, false)...)
+
 		// If use_default_network field is not provided or is set false.
 		// you should provide a existing network id or the build
 		// process will create a temporary network
-		if c.VpcId != "" && (c.CidrBlock != "" || c.VpcName != "") {
+		if existingVpc && (c.CidrBlock != "" || c.VpcName != "") {
 			// If vpc_id is provided, there is no need to provide cidr_block or vpc_name
 			errs = append(errs, errors.New("there is no need to provide 'cidr_block' or 'vpc_name',"+
-				" since the 'vpc_id' has been set"))
-		} else if c.VpcId == "" {
+				" since the 'vpc_id' or 'vpc_filter' has been set"))
+		} else if !existingVpc {
 			// Provide a default vpc_name or cidr_block to create a temporary vpc,
 			// if vpc_id is set null, and the vpc_name or cidr_block is not provided.
 			if c.VpcName == "" {
 				c.VpcName = packerId
 			}
 			if c.CidrBlock == "" {
-				c.CidrBlock = "192.168.0.0/16"
+				if c.CidrBlock, err = pickVpcCidr(avoidCidrs); err != nil {
+					errs = append(errs, err)
+				}
+			} else if vpcCidrs, err := parseCidrs([]string{c.CidrBlock}); err != nil {
+				errs = append(errs, fmt.Errorf("invalid 'vpc_cidr_block': %s", err))
+			} else if n := overlapsAny(vpcCidrs[0], avoidCidrs); n != nil {
+				errs = append(errs, fmt.Errorf("'vpc_cidr_block' overlaps %s of 'avoid_cidrs'", n))
 			}
 
 			// If vpc_id is not set, the build process will create a temporary subnet,
 			// along with temporary vpc
-			if c.SubnetId != "" {
-				errs = append(errs, errors.New("can't set 'subnet_id' without set 'vpc_id'"))
+			if existingSubnet {
+				errs = append(errs, errors.New("can't set 'subnet_id' or 'subnet_filter' without set 'vpc_id' or 'vpc_filter'"))
 			}
 			if c.SubnetName == "" {
 				c.SubnetName = packerId
 			}
 			if c.SubnetCidrBlock == "" {
-				c.SubnetCidrBlock = "192.168.8.0/24"
+				if c.CidrBlock == defaultVpcCidrBlock {
+					c.SubnetCidrBlock = defaultSubnetCidrBlock
+				} else {
+					c.SubnetCidrBlock = cidrAuto
+				}
 			}
 
 			// If vpc_id is not set, the build process will create a temporary
 			// security group along with temporary vpc
-			if c.SecurityGroupId != "" {
-				errs = append(errs, errors.New("can't set 'security_group_id without set 'vpc_id'"))
+			if existingSecurityGroup {
+				errs = append(errs, errors.New("can't set 'security_group_id' or 'security_group_filter' without set 'vpc_id' or 'vpc_filter'"))
 			}
-			if c.SecurityGroupName == "" {
+			if len(c.SecurityGroupIds) > 0 {
+				errs = append(errs, errors.New("can't set 'security_group_ids' without set 'vpc_id' or 'vpc_filter'"))
+			}
+			if c.SecurityGroupName == "" && !enterpriseSecurityGroup {
 				c.SecurityGroupName = packerId
 			}
 		}
 
 		// If vpc_id is provided and subnet_id is not provided, the build process
 		// will create a temporary subnet by the subnet_cidr_block
-		if c.VpcId != "" && c.SubnetId == "" {
+		if existingVpc && !existingSubnet {
 			if c.SubnetCidrBlock == "" {
 				errs = append(errs, errors.New("'subnet_cidr_block' must be provide, if 'vpc_id' is "+
-					"provided and 'subnet_id' is null"))
+					"provided and 'subnet_id' is null, set it to 'auto' to pick a free block"))
 			}
 			if c.SubnetName == "" {
 				c.SubnetName = packerId
@@ -204,7 +610,7 @@ func (c *BaiduCloudRunConfig) Prepare(ctx *interpolate.Context) []error {
 
 		// If vpc_id is provided and security_group_id is not provided, the build process
 		// will create a temporary security group
-		if c.VpcId != "" && c.SecurityGroupId == "" {
+		if existingVpc && !existingSecurityGroup && !enterpriseSecurityGroup {
 			if c.SecurityGroupName == "" {
 				c.SecurityGroupName = packerId
 			}
@@ -231,6 +637,99 @@ func (c *BaiduCloudRunConfig) Prepare(ctx *interpolate.Context) []error {
 		}
 	}
 
+	if c.TemporaryNatGateway {
+		if c.UseDefaultNetwork {
+			errs = append(errs, errors.New("'temporary_nat_gateway' can't be used along with 'use_default_network'"))
+		}
+		if c.AssociatePublicIpAddress {
+			errs = append(errs, errors.New("there is no need to set 'temporary_nat_gateway', "+
+				"since the 'associate_public_ip_address' field is true"))
+		}
+		if c.TemporaryNatGatewayBandwidthInMbps == 0 {
+			c.TemporaryNatGatewayBandwidthInMbps = 100
+		}
+	} else if c.TemporaryNatGatewayBandwidthInMbps != 0 {
+		errs = append(errs, errors.New("no need to set 'temporary_nat_gateway_bandwidth_in_mbps', "+
+			"since the 'temporary_nat_gateway' field is false"))
+	}
+
+	switch c.SSHInterface {
+	case "":
+	case sshInterfacePublicIp:
+		if !c.AssociatePublicIpAddress {
+			errs = append(errs, errors.New("'ssh_interface' can't be 'public_ip', "+
+				"since the 'associate_public_ip_address' field is false"))
+		}
+	case sshInterfacePrivateIp:
+	case sshInterfaceIpv6:
+		if !c.EnableIpv6 {
+			errs = append(errs, errors.New("'ssh_interface' can't be 'ipv6', since the 'enable_ipv6' field is false"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("'ssh_interface' must be one of '%s', '%s' and '%s'",
+			sshInterfacePublicIp, sshInterfacePrivateIp, sshInterfaceIpv6))
+	}
+
+	if c.TemporaryBastion != nil {
+		errs = append(errs, c.TemporaryBastion.Prepare(c.SourceImageId)...)
+		if c.UseDefaultNetwork {
+			errs = append(errs, errors.New("'temporary_bastion' can't be used along with 'use_default_network'"))
+		}
+		if c.AssociatePublicIpAddress {
+			errs = append(errs, errors.New("there is no need to set 'temporary_bastion', "+
+				"since the 'associate_public_ip_address' field is true"))
+		}
+		if c.isWindows() {
+			errs = append(errs, errors.New("'temporary_bastion' can only be used with the ssh communicator"))
+		}
+		if c.Comm.SSHBastionHost != "" {
+			errs = append(errs, errors.New("'temporary_bastion' can't be used along with 'ssh_bastion_host'"))
+		}
+		if c.Comm.Type == "none" {
+			errs = append(errs, errors.New("there is no need to set 'temporary_bastion', since the communicator is none"))
+		}
+	}
+
+	for i := range c.NetworkInterfaces {
+		errs = append(errs, c.NetworkInterfaces[i].Prepare(i)...)
+	}
+	if len(c.NetworkInterfaces) > 0 && c.UseDefaultNetwork {
+		errs = append(errs, errors.New("'network_interfaces' can't be used along with 'use_default_network'"))
+	}
+
+	if c.SpotStrategy == "" {
+		c.SpotStrategy = SpotStrategyNone
+	}
+	switch c.SpotStrategy {
+	case SpotStrategyNone:
+		if c.SpotPriceLimit != 0 || c.SpotFallbackToOnDemand {
+			errs = append(errs, errors.New("no need to set fields 'spot_price_limit' or 'spot_fallback_to_on_demand', "+
+				"since the 'spot_strategy' field is none"))
+		}
+	case SpotStrategyMarketPrice:
+		if c.SpotPriceLimit != 0 {
+			errs = append(errs, errors.New("no need to set field 'spot_price_limit', since the 'spot_strategy' field is market_price"))
+		}
+	case SpotStrategyCustomPrice:
+		if c.SpotPriceLimit <= 0 {
+			errs = append(errs, errors.New("'spot_price_limit' must be greater than 0, since the 'spot_strategy' field is custom_price"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown spot_strategy: %s", c.SpotStrategy))
+	}
+
+	if c.DeploymentSetId != "" && c.AutoCreateDeploymentSet {
+		errs = append(errs, errors.New("only one of 'deployment_set_id' or 'auto_create_deployment_set' can be specified"))
+	}
+	if c.DedicatedHostId != "" {
+		if c.DeploymentSetId != "" || c.AutoCreateDeploymentSet {
+			errs = append(errs, errors.New("deployment set can't be used along with 'dedicated_host_id'"))
+		}
+		if c.SpotStrategy != SpotStrategyNone {
+			errs = append(errs, errors.New("spot instance can't be launched on the 'dedicated_host_id'"))
+		}
+	}
+
 	if c.RootDiskSizeInGb < 20 {
 		c.RootDiskSizeInGb = 20
 	}
@@ -249,3 +748,65 @@ func (c *BaiduCloudRunConfig) Prepare(ctx *interpolate.Context) []error {
 
 	return errs
 }
+
+// configOption is an option of the config, and whether it's set
+type configOption struct {
+	name string
+	set  bool
+}
+
+// launchOptions returns the options which only apply to launching a new
+// instance
+func (c *BaiduCloudRunConfig) launchOptions() []configOption {
+	return []configOption{
+		{"'instance_spec' or 'instance_specs'",
+			c.InstanceSpec != "" || len(c.InstanceSpecs) > 0},
+		{"'use_default_network'",
+			c.UseDefaultNetwork},
+		{"'vpc_id', 'vpc_filter', 'vpc_name' or 'vpc_cidr_block'",
+			c.VpcId != "" || !c.VpcFilter.Empty() || c.VpcName != "" || c.CidrBlock != ""},
+		{"'subnet_id', 'subnet_filter', 'subnet_name', 'subnet_cidr_block' or 'avoid_cidrs'",
+			c.SubnetId != "" || !c.SubnetFilter.Empty() || c.SubnetName != "" || c.SubnetCidrBlock != "" || len(c.AvoidCidrs) > 0},
+		{"'security_group_id', 'security_group_filter', 'security_group_name', 'security_group_ids' or 'enterprise_security_group_ids'",
+			c.SecurityGroupId != "" || !c.SecurityGroupFilter.Empty() || c.SecurityGroupName != "" || len(c.SecurityGroupIds) > 0 ||
+				len(c.EnterpriseSecurityGroupIds) > 0},
+		{"'temporary_nat_gateway'",
+			c.TemporaryNatGateway},
+		{"'temporary_bastion'",
+			c.TemporaryBastion != nil},
+		{"'network_interfaces'",
+			len(c.NetworkInterfaces) > 0},
+		{"'user_data', 'user_data_file' or 'user_data_files'",
+			c.UserData != "" || c.UserDataFile != "" || len(c.UserDataFiles) > 0},
+		{"'spot_strategy'",
+			c.SpotStrategy != "" && c.SpotStrategy != SpotStrategyNone},
+		{"'deployment_set_id' or 'auto_create_deployment_set'",
+			c.DeploymentSetId != "" || c.AutoCreateDeploymentSet},
+		{"'dedicated_host_id'",
+			c.DedicatedHostId != ""},
+	}
+}
+
+// existingInstanceId returns the id of the existing instance the build runs
+// on, either reused or captured, or an empty string if a new instance is
+// launched
+func (c *BaiduCloudRunConfig) existingInstanceId() string {
+	if c.SourceInstanceId != "" {
+		return c.SourceInstanceId
+	}
+	return c.ReuseInstanceId
+}
+
+// isWindows returns whether the instance is a windows instance, which is
+// connected by winrm
+func (c *BaiduCloudRunConfig) isWindows() bool {
+	return c.Comm.Type == "winrm"
+}
+
+// candidateInstanceSpecs returns the instance specs to try in order
+func (c *BaiduCloudRunConfig) candidateInstanceSpecs() []string {
+	if c.InstanceSpec != "" {
+		return []string{c.InstanceSpec}
+	}
+	return c.InstanceSpecs
+}
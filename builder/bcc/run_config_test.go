@@ -3,7 +3,10 @@ package bcc
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/packer-plugin-sdk/communicator"
 )
@@ -39,6 +42,29 @@ func TestRunConfigPrepare(t *testing.T) {
 	}
 }
 
+func TestRunConfigPrepare_InstanceSpecs(t *testing.T) {
+	c := getTestRunConfig()
+
+	c.InstanceSpecs = []string{"bcc.ic4.c4m8"}
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+
+	c.InstanceSpec = ""
+	c.InstanceSpecs = []string{"bcc.ic4.c4m8", "bcc.g4.c4m16"}
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+	if specs := c.candidateInstanceSpecs(); len(specs) != 2 || specs[0] != "bcc.ic4.c4m8" {
+		t.Fatalf("Instance specs should keep the order: %v", specs)
+	}
+
+	c.InstanceSpecs = []string{"bcc.ic4.c4m8", ""}
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+}
+
 func TestRunConfigPrepare_UserData(t *testing.T) {
 	c := getTestRunConfig()
 
@@ -237,6 +263,77 @@ func TestRunConfigPrepare_Eip(t *testing.T) {
 
 }
 
+func TestRunConfigPrepare_Spot(t *testing.T) {
+	c := getTestRunConfig()
+
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+	if c.SpotStrategy != SpotStrategyNone {
+		t.Fatalf("The default spot strategy should be none, got %s", c.SpotStrategy)
+	}
+
+	c.SpotFallbackToOnDemand = true
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+
+	c.SpotStrategy = SpotStrategyMarketPrice
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+
+	c.SpotPriceLimit = 0.5
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+
+	c.SpotStrategy = SpotStrategyCustomPrice
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+
+	c.SpotPriceLimit = 0
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+
+	c.SpotStrategy = "unknown"
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+}
+
+func TestRunConfigPrepare_Placement(t *testing.T) {
+	c := getTestRunConfig()
+
+	c.DeploymentSetId = "dset-test"
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+
+	c.AutoCreateDeploymentSet = true
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+
+	c.DeploymentSetId = ""
+	c.DedicatedHostId = "d-test"
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+
+	c.AutoCreateDeploymentSet = false
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+
+	c.SpotStrategy = SpotStrategyMarketPrice
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+}
+
 // func TestRunConfigPrepare_DataDisk(t *testing.T) {
 // 	c := getTestRunConfig()
 
@@ -249,3 +346,502 @@ func TestRunConfigPrepare_Eip(t *testing.T) {
 // 		t.Fatalf("Should raise an error: %s", errs)
 // 	}
 // }
+
+func TestRunConfigPrepare_Windows(t *testing.T) {
+	c := getTestRunConfig()
+	c.Comm = communicator.Config{Type: "winrm"}
+
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+	if c.Comm.WinRMUser != "Administrator" {
+		t.Fatalf("WinRM user should be Administrator by default, got %s", c.Comm.WinRMUser)
+	}
+	if c.Comm.WinRMPassword == "" {
+		t.Fatal("WinRM password should be generated")
+	}
+	if c.Comm.SSHTemporaryKeyPairName != "" {
+		t.Fatal("Temporary keypair shouldn't be used by windows instance")
+	}
+
+	c.WindowsSysprep = true
+	c.KeypairId = "k-3uC6Rl5O"
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+
+	c = getTestRunConfig()
+	c.WindowsSysprep = true
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+}
+
+func TestRunConfigPrepare_SSHTemporaryPassword(t *testing.T) {
+	c := getTestRunConfig()
+	c.SSHTemporaryPassword = true
+	c.ResetTemporaryPassword = true
+
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+	if c.Comm.SSHPassword == "" {
+		t.Fatal("SSH password should be generated")
+	}
+	if c.Comm.SSHTemporaryKeyPairName != "" {
+		t.Fatal("Temporary keypair shouldn't be created along with temporary password")
+	}
+
+	c = getTestRunConfig()
+	c.SSHTemporaryPassword = true
+	c.Comm.SSHPassword = "Passw0rd!"
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+
+	c = getTestRunConfig()
+	c.ResetTemporaryPassword = true
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+}
+
+func TestRunConfigPrepare_ImportTemporaryKeyPair(t *testing.T) {
+	c := getTestRunConfig()
+	c.ImportTemporaryKeyPair = true
+	c.Comm.SSHTemporaryKeyPairType = "ed25519"
+
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+	if c.Comm.SSHTemporaryKeyPairName == "" {
+		t.Fatal("Temporary keypair name should be set")
+	}
+
+	c = getTestRunConfig()
+	c.ImportTemporaryKeyPair = true
+	c.Comm.SSHTemporaryKeyPairType = "dsa"
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+}
+
+func TestRunConfigPrepare_SSHPublicKeyFile(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+	defer os.Remove(tf.Name())
+	tf.Close()
+
+	c := getTestRunConfig()
+	c.SSHPublicKeyFile = tf.Name()
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+
+	c = getTestRunConfig()
+	c.SSHPublicKeyFile = tf.Name()
+	c.Comm.SSHAgentAuth = true
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+	if c.Comm.SSHTemporaryKeyPairName == "" {
+		t.Fatal("Temporary keypair name should be set")
+	}
+
+	c.SSHPublicKeyFile = "/path/to/nonexistent"
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+}
+
+func TestRunConfigPrepare_SSHKeyPairName(t *testing.T) {
+	c := getTestRunConfig()
+	c.Comm.SSHKeyPairName = "packer"
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+
+	c = getTestRunConfig()
+	c.Comm.SSHKeyPairName = "packer"
+	c.Comm.SSHAgentAuth = true
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+
+	// 'keypair_id' takes precedence
+	c = getTestRunConfig()
+	c.Comm.SSHKeyPairName = "packer"
+	c.KeypairId = "k-3uC6Rl5O"
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+}
+
+func TestRunConfigPrepare_UserDataTimeout(t *testing.T) {
+	c := getTestRunConfig()
+	c.WaitForUserData = true
+
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+	if c.UserDataTimeout != 30*time.Minute {
+		t.Fatalf("User data timeout should be 30m by default, got %s", c.UserDataTimeout)
+	}
+
+	c.UserDataTimeout = -time.Minute
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+}
+
+func TestRunConfigPrepare_UserDataFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	cloudConfig := filepath.Join(dir, "cloud-config.yaml")
+	script := filepath.Join(dir, "script.sh")
+	large := filepath.Join(dir, "large.sh")
+	ioutil.WriteFile(cloudConfig, []byte("#cloud-config\nhostname: {{ .BuildName }}\n"), 0600)
+	ioutil.WriteFile(script, []byte("#!/bin/sh\necho hello\n"), 0600)
+	ioutil.WriteFile(large, []byte("#!/bin/sh\n"+strings.Repeat("#", maxUserDataSize)), 0600)
+
+	c := getTestRunConfig()
+	c.UserDataFiles = []string{cloudConfig, script}
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+
+	c.UserData = "#!/bin/sh"
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+
+	c = getTestRunConfig()
+	c.UserDataFiles = []string{large}
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+}
+
+func TestRunConfigPrepare_NetworkFilter(t *testing.T) {
+	c := getTestRunConfig()
+	c.UseDefaultNetwork = false
+	c.VpcFilter = NetworkFilter{Tags: map[string]string{"env": "prod"}}
+	c.SubnetFilter = NetworkFilter{Zone: "cn-bj-a", MostFreeIps: true}
+	c.SecurityGroupFilter = NetworkFilter{Name: "corp-baseline"}
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+	if c.VpcName != "" || c.SubnetName != "" || c.SecurityGroupName != "" {
+		t.Fatalf("Shouldn't create temporary network: %s, %s, %s", c.VpcName, c.SubnetName, c.SecurityGroupName)
+	}
+
+	c.VpcId = "vpc-id-test"
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+	c.VpcId = ""
+
+	c.VpcFilter.MostFreeIps = true
+	c.SecurityGroupFilter.Cidr = "10.0.0.0/24"
+	if errs := c.Prepare(nil); len(errs) != 2 {
+		t.Fatalf("Should raise 2 errors: %s", errs)
+	}
+
+	c = getTestRunConfig()
+	c.UseDefaultNetwork = false
+	c.SubnetFilter = NetworkFilter{Name: "subnet"}
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+
+	c = getTestRunConfig()
+	c.UseDefaultNetwork = true
+	c.VpcFilter = NetworkFilter{Name: "vpc"}
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+}
+
+func TestRunConfigPrepare_SecurityGroupIds(t *testing.T) {
+	c := getTestRunConfig()
+	c.UseDefaultNetwork = false
+	c.VpcId = "vpc-id-test"
+	c.SubnetId = "subnet-id-test"
+	c.SecurityGroupIds = []string{"g-baseline"}
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+	if c.SecurityGroupName == "" {
+		t.Fatalf("The temporary security group should be created")
+	}
+
+	c.EnterpriseSecurityGroupIds = []string{"esg-test"}
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+
+	c = getTestRunConfig()
+	c.UseDefaultNetwork = false
+	c.EnterpriseSecurityGroupIds = []string{"esg-test"}
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+	if c.SecurityGroupName != "" {
+		t.Fatalf("The temporary security group shouldn't be created")
+	}
+
+	c = getTestRunConfig()
+	c.UseDefaultNetwork = false
+	c.SecurityGroupIds = []string{"g-baseline"}
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+}
+
+func TestRunConfigPrepare_AutoCidr(t *testing.T) {
+	c := getTestRunConfig()
+	c.UseDefaultNetwork = false
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+	if c.CidrBlock != defaultVpcCidrBlock || c.SubnetCidrBlock != defaultSubnetCidrBlock {
+		t.Fatalf("Cidr blocks are wrong: %s, %s", c.CidrBlock, c.SubnetCidrBlock)
+	}
+
+	c = getTestRunConfig()
+	c.UseDefaultNetwork = false
+	c.AvoidCidrs = []string{"192.168.100.0/24", "172.16.0.0/12"}
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+	if c.CidrBlock != "10.0.0.0/16" || c.SubnetCidrBlock != cidrAuto {
+		t.Fatalf("Cidr blocks are wrong: %s, %s", c.CidrBlock, c.SubnetCidrBlock)
+	}
+
+	c = getTestRunConfig()
+	c.UseDefaultNetwork = false
+	c.VpcId = "vpc-id-test"
+	c.SubnetCidrBlock = cidrAuto
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+
+	c.AvoidCidrs = []string{"invalid"}
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+
+	c.AvoidCidrs = []string{"192.168.8.0/22"}
+	c.SubnetCidrBlock = "192.168.9.0/24"
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+}
+
+func TestRunConfigPrepare_TemporaryNatGateway(t *testing.T) {
+	c := getTestRunConfig()
+	c.UseDefaultNetwork = false
+	c.TemporaryNatGateway = true
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+	if c.TemporaryNatGatewayBandwidthInMbps != 100 {
+		t.Fatalf("Default bandwidth is wrong: %d", c.TemporaryNatGatewayBandwidthInMbps)
+	}
+
+	c.AssociatePublicIpAddress = true
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+
+	c = getTestRunConfig()
+	c.UseDefaultNetwork = true
+	c.TemporaryNatGateway = true
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+
+	c = getTestRunConfig()
+	c.TemporaryNatGatewayBandwidthInMbps = 10
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+}
+
+func TestRunConfigPrepare_TemporaryBastion(t *testing.T) {
+	c := getTestRunConfig()
+	c.UseDefaultNetwork = false
+	c.TemporaryBastion = &TemporaryBastionConfig{InstanceSpec: "bcc.g5.c1m1"}
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+	if c.TemporaryBastion.SourceImageId != c.SourceImageId || c.TemporaryBastion.SSHUsername != "root" {
+		t.Fatalf("Bastion defaults are wrong: %+v", c.TemporaryBastion)
+	}
+
+	c.TemporaryBastion.InstanceSpec = ""
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+	c.TemporaryBastion.InstanceSpec = "bcc.g5.c1m1"
+
+	c.Comm.SSHBastionHost = "1.2.3.4"
+	c.Comm.SSHBastionPassword = "password"
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+	c.Comm.SSHBastionHost = ""
+	c.Comm.SSHBastionPassword = ""
+
+	c.AssociatePublicIpAddress = true
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+}
+
+func TestRunConfigPrepare_SSHInterface(t *testing.T) {
+	c := getTestRunConfig()
+	c.SSHInterface = sshInterfaceIpv6
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+
+	c.EnableIpv6 = true
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+
+	c.SSHInterface = "foo"
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+
+	c.SSHInterface = sshInterfacePrivateIp
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+}
+
+func TestRunConfigPrepare_NetworkInterfaces(t *testing.T) {
+	c := getTestRunConfig()
+	c.UseDefaultNetwork = false
+	c.NetworkInterfaces = []NetworkInterfaceConfig{
+		{},
+		{SubnetId: "sbn-xxx", SecurityGroupIds: []string{"g-xxx"}, SecondaryPrivateIpCount: 2},
+	}
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+
+	c.NetworkInterfaces[1].SecondaryPrivateIpCount = -1
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+
+	c = getTestRunConfig()
+	c.UseDefaultNetwork = true
+	c.NetworkInterfaces = []NetworkInterfaceConfig{{}}
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+}
+
+func TestRunConfigPrepare_NoneCommunicator(t *testing.T) {
+	c := getTestRunConfig()
+	c.Comm.Type = "none"
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+	if c.Comm.SSHTemporaryKeyPairName != "" {
+		t.Fatalf("Shouldn't create temporary keypair: %s", c.Comm.SSHTemporaryKeyPairName)
+	}
+
+	c.UseDefaultNetwork = false
+	c.TemporaryBastion = &TemporaryBastionConfig{InstanceSpec: "bcc.g5.c1m1"}
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+
+	c = getTestRunConfig()
+	c.Comm.Type = "none"
+	c.WaitForUserData = true
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise an error: %s", errs)
+	}
+}
+
+func TestRunConfigPrepare_ReuseInstanceId(t *testing.T) {
+	c := getTestRunConfig()
+	c.InstanceSpec = ""
+	c.ReuseInstanceId = "i-xxx"
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+	if c.VpcName != "" || c.SubnetName != "" || c.SecurityGroupName != "" {
+		t.Fatalf("Shouldn't create temporary network: %s %s %s", c.VpcName, c.SubnetName, c.SecurityGroupName)
+	}
+
+	c = getTestRunConfig()
+	c.ReuseInstanceId = "i-xxx"
+	c.VpcId = "vpc-xxx"
+	c.TemporaryNatGateway = true
+	if errs := c.Prepare(nil); len(errs) != 3 {
+		t.Fatalf("Should raise 3 errors: %s", errs)
+	}
+}
+
+func TestRunConfigPrepare_SourceInstanceId(t *testing.T) {
+	c := getTestRunConfig()
+	c.SourceImageId = ""
+	c.InstanceSpec = ""
+	c.SourceInstanceId = "i-xxx"
+	c.StopSourceInstance = true
+	c.Comm.SSHPassword = "password"
+	if errs := c.Prepare(nil); len(errs) != 0 {
+		t.Fatalf("Shouldn't raise error: %s", errs)
+	}
+	if c.Comm.SSHTemporaryKeyPairName != "" {
+		t.Fatalf("Shouldn't create temporary keypair: %s", c.Comm.SSHTemporaryKeyPairName)
+	}
+	if c.VpcName != "" || c.SubnetName != "" || c.SecurityGroupName != "" {
+		t.Fatalf("Shouldn't create temporary network: %s %s %s", c.VpcName, c.SubnetName, c.SecurityGroupName)
+	}
+
+	c = getTestRunConfig()
+	c.SourceInstanceId = "i-xxx"
+	c.ReuseInstanceId = "i-yyy"
+	c.Comm.SSHPassword = "password"
+	if errs := c.Prepare(nil); len(errs) != 3 {
+		t.Fatalf("Should raise 3 errors: %s", errs)
+	}
+
+	c = getTestRunConfig()
+	c.SourceImageId = ""
+	c.InstanceSpec = ""
+	c.SourceInstanceId = "i-xxx"
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise 1 error without ssh credentials: %s", errs)
+	}
+
+	c = getTestRunConfig()
+	c.SourceImageId = ""
+	c.InstanceSpec = ""
+	c.SourceInstanceId = "i-xxx"
+	c.Comm.SSHPassword = "password"
+	c.Comm.SSHClearAuthorizedKeys = true
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise 1 error with ssh_clear_authorized_keys: %s", errs)
+	}
+
+	c = getTestRunConfig()
+	c.StopSourceInstance = true
+	if errs := c.Prepare(nil); len(errs) != 1 {
+		t.Fatalf("Should raise 1 error: %s", errs)
+	}
+}
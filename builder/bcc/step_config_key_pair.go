@@ -3,29 +3,47 @@ package bcc
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"runtime"
+	"strings"
 
 	"github.com/baidubce/bce-sdk-go/services/bcc"
 	"github.com/baidubce/bce-sdk-go/services/bcc/api"
 	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/communicator/sshkey"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/hashicorp/packer-plugin-sdk/uuid"
 )
 
 type stepConfigKeyPair struct {
-	Debug        bool
-	Comm         *communicator.Config
-	DebugKeyPath string
-	KeyPairId    string
-	Description  string
-	isCreate     bool
+	Debug         bool
+	Comm          *communicator.Config
+	DebugKeyPath  string
+	KeyPairId     string
+	Description   string
+	Import        bool
+	PublicKeyFile string
+	isCreate      bool
 }
 
 func (s *stepConfigKeyPair) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	ui := state.Get("ui").(packersdk.Ui)
 
+	if len(s.KeyPairId) == 0 && s.Comm.SSHKeyPairName != "" {
+		ui.Say(fmt.Sprintf("Looking up keypair by name: %s", s.Comm.SSHKeyPairName))
+		keyPairId, err := findKeyPairId(ctx, state.Get("client").(*bcc.Client), s.Comm.SSHKeyPairName)
+		if err != nil {
+			return halt(state, err, "Failed to look up keypair")
+		}
+		ui.Message(fmt.Sprintf("Found keypair(%s) with name %s", keyPairId, s.Comm.SSHKeyPairName))
+		s.KeyPairId = keyPairId
+	}
+	if len(s.KeyPairId) != 0 {
+		state.Put("key_pair_id", s.KeyPairId)
+	}
+
 	if s.Comm.SSHPrivateKeyFile != "" {
 		ui.Say("Try to use existing SSH private key")
 		privateKeyBytes, err := s.Comm.ReadSSHPrivateKeyFile()
@@ -34,16 +52,18 @@ func (s *stepConfigKeyPair) Run(ctx context.Context, state multistep.StateBag) m
 		}
 		s.Comm.SSHPrivateKey = privateKeyBytes
 		ui.Say(fmt.Sprintf("Loaded %d bytes private key data", len(s.Comm.SSHPrivateKey)))
-		return multistep.ActionContinue
+		if s.PublicKeyFile == "" {
+			return multistep.ActionContinue
+		}
 	}
 
-	if s.Comm.SSHAgentAuth && len(s.KeyPairId) == 0 {
+	if s.Comm.SSHAgentAuth && s.PublicKeyFile == "" && len(s.KeyPairId) == 0 {
 		ui.Say("Using SSH Agent with key pair in source image")
 		return multistep.ActionContinue
 	}
 
-	if s.Comm.SSHAgentAuth && len(s.KeyPairId) != 0 {
-		ui.Say(fmt.Sprintf("Using SSH Agent with existing key pair(%s)", s.Comm.SSHKeyPairName))
+	if s.Comm.SSHAgentAuth && s.PublicKeyFile == "" && len(s.KeyPairId) != 0 {
+		ui.Say(fmt.Sprintf("Using SSH Agent with existing key pair(%s)", s.KeyPairId))
 		return multistep.ActionContinue
 	}
 
@@ -54,26 +74,46 @@ func (s *stepConfigKeyPair) Run(ctx context.Context, state multistep.StateBag) m
 	}
 
 	client := state.Get("client").(*bcc.Client)
-	// create temporary keypair
-	ui.Say(fmt.Sprintf("Creating temporary keypair: %s", s.Comm.SSHTemporaryKeyPairName))
+	var keypair *api.KeypairModel
+	if s.PublicKeyFile != "" || s.Import {
+		// import the local public key, so that the private key never
+		// leaves the build host
+		ui.Say(fmt.Sprintf("Importing temporary keypair: %s", s.Comm.SSHTemporaryKeyPairName))
 
-	createResult, err := client.CreateKeypair(s.getCreateKeypairArgs(state))
-	if err != nil {
-		return halt(state, err, "Failed to create temporary keypair")
+		args, err := s.getImportKeypairArgs(state)
+		if err != nil {
+			return halt(state, err, "Failed to prepare public key")
+		}
+		importResult, err := client.ImportKeypair(args)
+		if err != nil {
+			return halt(state, err, "Failed to import temporary keypair")
+		}
+		keypair = &importResult.Keypair
+	} else {
+		// create temporary keypair
+		ui.Say(fmt.Sprintf("Creating temporary keypair: %s", s.Comm.SSHTemporaryKeyPairName))
+
+		createResult, err := client.CreateKeypair(s.getCreateKeypairArgs(state))
+		if err != nil {
+			return halt(state, err, "Failed to create temporary keypair")
+		}
+		keypair = &createResult.Keypair
+		s.Comm.SSHPrivateKey = []byte(keypair.PrivateKey)
+		s.Comm.SSHPublicKey = []byte(keypair.PublicKey)
 	}
 
 	// set the keypair id for delete it later
-	s.KeyPairId = createResult.Keypair.KeypairId
+	s.KeyPairId = keypair.KeypairId
 	s.isCreate = true
 
 	// set some state data for use in future steps
-	s.Comm.SSHPrivateKey = []byte(createResult.Keypair.PrivateKey)
 	state.Put("temporary_key_pair_id", s.KeyPairId)
 
 	ui.Message(fmt.Sprintf("Success to create temporary keypair, the id is: %s", s.KeyPairId))
 
-	// Set debug key path to use debug mode
-	if s.Debug {
+	// Set debug key path to use debug mode, the private key can only be saved
+	// if it's not provided by the user
+	if s.Debug && s.PublicKeyFile == "" {
 		ui.Message(fmt.Sprintf("Saving private key for debug at path: %s", s.DebugKeyPath))
 		file, err := os.Create(s.DebugKeyPath)
 		if err != nil {
@@ -118,7 +158,7 @@ func (s *stepConfigKeyPair) Cleanup(state multistep.StateBag) {
 		ui.Error(fmt.Sprintf("Failed to cleanup keypair(%s), please delete it manually: %s", s.KeyPairId, err))
 	}
 
-	if s.Debug {
+	if s.Debug && s.PublicKeyFile == "" {
 		if err := os.Remove(s.DebugKeyPath); err != nil {
 			ui.Error(fmt.Sprintf("Failed to remove debug key file(%s), please delete the file manually: %s", s.DebugKeyPath, err))
 		}
@@ -133,3 +173,85 @@ func (s *stepConfigKeyPair) getCreateKeypairArgs(state multistep.StateBag) *api.
 		Description: s.Description,
 	}
 }
+
+// getImportKeypairArgs reads the public key from `PublicKeyFile`, or generates
+// a new key pair locally if it's not set
+func (s *stepConfigKeyPair) getImportKeypairArgs(state multistep.StateBag) (*api.ImportKeypairArgs, error) {
+	var publicKey []byte
+	if s.PublicKeyFile != "" {
+		data, err := ioutil.ReadFile(s.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		fields := strings.Fields(string(data))
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("invalid public key in %s", s.PublicKeyFile)
+		}
+		// the comment of the key is replaced with the keypair name, so that
+		// StepCleanupTempKeys can remove the key from authorized_keys
+		publicKey = []byte(fmt.Sprintf("%s %s %s", fields[0], fields[1], s.Comm.SSHTemporaryKeyPairName))
+		s.Comm.SSHPublicKey = publicKey
+	} else {
+		algorithm := s.Comm.SSHTemporaryKeyPairType
+		if algorithm == "" {
+			algorithm = sshkey.RSA.String()
+		}
+		a, err := sshkey.AlgorithmString(algorithm)
+		if err != nil {
+			return nil, err
+		}
+		pair, err := sshkey.GeneratePair(a, nil, s.Comm.SSHTemporaryKeyPairBits)
+		if err != nil {
+			return nil, err
+		}
+		// the keypair name is used as comment, which is required by
+		// StepCleanupTempKeys to remove the key from authorized_keys
+		publicKey = []byte(fmt.Sprintf("%s %s", strings.TrimSpace(string(pair.Public)), s.Comm.SSHTemporaryKeyPairName))
+		s.Comm.SSHPrivateKey = pair.Private
+		s.Comm.SSHPublicKey = publicKey
+	}
+
+	return &api.ImportKeypairArgs{
+		ClientToken: uuid.TimeOrderedUUID(),
+		Name:        s.Comm.SSHTemporaryKeyPairName,
+		Description: s.Description,
+		PublicKey:   strings.TrimSpace(string(publicKey)),
+	}, nil
+}
+
+// findKeyPairId looks up the id of the keypair with the name, which must
+// match exactly one keypair
+func findKeyPairId(ctx context.Context, client *bcc.Client, name string) (string, error) {
+	var keyPairIds []string
+	args := &api.ListKeypairArgs{MaxKeys: 1000}
+	for {
+		var listResult *api.ListKeypairResult
+		err := Retry(ctx, func(ctx context.Context) error {
+			var e error
+			listResult, e = client.ListKeypairs(args)
+			return e
+		})
+		if err != nil {
+			return "", err
+		}
+		for _, keypair := range listResult.Keypairs {
+			if keypair.Name == name {
+				keyPairIds = append(keyPairIds, keypair.KeypairId)
+			}
+		}
+		if !listResult.IsTruncated {
+			break
+		}
+		args.Marker = listResult.NextMarker
+	}
+
+	switch len(keyPairIds) {
+	case 0:
+		return "", fmt.Errorf("no keypair named %s is found", name)
+	case 1:
+		return keyPairIds[0], nil
+	default:
+		return "", fmt.Errorf("%d keypairs named %s are found: %s, please use 'keypair_id' instead",
+			len(keyPairIds), name, strings.Join(keyPairIds, ", "))
+	}
+}
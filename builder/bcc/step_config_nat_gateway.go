@@ -0,0 +1,272 @@
+package bcc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/baidubce/bce-sdk-go/services/eip"
+	"github.com/baidubce/bce-sdk-go/services/vpc"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
+)
+
+// eipStatusAvailable is the status of an eip which isn't bound
+const eipStatusAvailable = "available"
+
+// stepConfigNatGateway creates a temporary nat gateway with an eip, and routes
+// the traffic of the subnet to the internet through it, so that the instance
+// without public ip can reach the internet
+type stepConfigNatGateway struct {
+	Enabled         bool
+	Name            string
+	BandwidthInMbps int
+	vpcId           string
+	eip             string
+	natId           string
+	snatRuleId      string
+	routeRuleId     string
+}
+
+func (s *stepConfigNatGateway) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	if !s.Enabled {
+		return multistep.ActionContinue
+	}
+
+	vpcClient := state.Get("vpc_client").(*vpc.Client)
+	eipClient := state.Get("eip_client").(*eip.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+	vpcId := state.Get("vpc_id").(string)
+	subnetId := state.Get("subnet_id").(string)
+	s.vpcId = vpcId
+
+	subnetDetail, err := vpcClient.GetSubnetDetail(subnetId)
+	if err != nil {
+		return halt(state, err, fmt.Sprintf("Failed to get subnet(%s)", subnetId))
+	}
+	// the rules match the cidr block, which is kept if the subnet is
+	// recreated in another zone
+	subnetCidr := subnetDetail.Subnet.Cidr
+
+	ui.Say("Starting to create eip for nat gateway...")
+	// the client tokens are kept across retries, so that a request which
+	// timed out but succeeded doesn't create the resource twice
+	createEipArgs := &eip.CreateEipArgs{
+		Name:            s.Name,
+		BandWidthInMbps: s.BandwidthInMbps,
+		Billing: &eip.Billing{
+			PaymentTiming: "Postpaid",
+			BillingMethod: "ByTraffic",
+		},
+		ClientToken: uuid.TimeOrderedUUID(),
+	}
+	var createEipResult *eip.CreateEipResult
+	err = Retry(ctx, func(ctx context.Context) error {
+		var e error
+		createEipResult, e = eipClient.CreateEip(createEipArgs)
+		return e
+	})
+	if err != nil {
+		return halt(state, err, "Failed to create eip")
+	}
+	s.eip = createEipResult.Eip
+	if err := waitForEip(ctx, eipClient, s.eip, eipStatusAvailable, 300); err != nil {
+		return halt(state, err, fmt.Sprintf("Failed to wait for eip(%s) available", s.eip))
+	}
+	ui.Message(fmt.Sprintf("Success to create eip: %s", s.eip))
+
+	ui.Say("Starting to create nat gateway...")
+	createNatArgs := &vpc.CreateNatGatewayArgs{
+		ClientToken: uuid.TimeOrderedUUID(),
+		Name:        s.Name,
+		VpcId:       vpcId,
+		Spec:        vpc.NAT_GATEWAY_SPEC_SMALL,
+		Eips:        []string{s.eip},
+		Billing:     &vpc.Billing{PaymentTiming: vpc.PAYMENT_TIMING_POSTPAID},
+	}
+	var createNatResult *vpc.CreateNatGatewayResult
+	err = Retry(ctx, func(ctx context.Context) error {
+		var e error
+		createNatResult, e = vpcClient.CreateNatGateway(createNatArgs)
+		return e
+	})
+	if err != nil {
+		return halt(state, err, "Failed to create nat gateway")
+	}
+	s.natId = createNatResult.NatId
+	if err := waitForNatGateway(ctx, vpcClient, s.natId, vpc.NAT_STATUS_ACTIVE, 600); err != nil {
+		return halt(state, err, fmt.Sprintf("Failed to wait for nat gateway(%s) active", s.natId))
+	}
+	ui.Message(fmt.Sprintf("Success to create nat gateway: %s", s.natId))
+
+	ui.Say("Starting to create snat rule...")
+	createSnatArgs := &vpc.CreateNatGatewaySnatRuleArgs{
+		ClientToken:       uuid.TimeOrderedUUID(),
+		RuleName:          s.Name,
+		SourceCIDR:        subnetCidr,
+		PublicIpAddresses: []string{s.eip},
+	}
+	var createSnatResult *vpc.CreateNatGatewaySnatRuleResult
+	err = Retry(ctx, func(ctx context.Context) error {
+		var e error
+		createSnatResult, e = vpcClient.CreateNatGatewaySnatRule(s.natId, createSnatArgs)
+		return e
+	})
+	if err != nil {
+		return halt(state, err, "Failed to create snat rule")
+	}
+	s.snatRuleId = createSnatResult.RuleId
+
+	ui.Say("Starting to create route rule...")
+	routeTable, err := vpcClient.GetRouteTableDetail("", vpcId)
+	if err != nil {
+		return halt(state, err, fmt.Sprintf("Failed to get route table of vpc(%s)", vpcId))
+	}
+	createRouteArgs := &vpc.CreateRouteRuleArgs{
+		ClientToken:        uuid.TimeOrderedUUID(),
+		RouteTableId:       routeTable.RouteTableId,
+		SourceAddress:      subnetCidr,
+		DestinationAddress: "0.0.0.0/0",
+		NexthopId:          s.natId,
+		NexthopType:        vpc.NEXTHOP_TYPE_NAT,
+		Description:        "route for packer",
+	}
+	var createRouteResult *vpc.CreateRouteRuleResult
+	err = Retry(ctx, func(ctx context.Context) error {
+		var e error
+		createRouteResult, e = vpcClient.CreateRouteRule(createRouteArgs)
+		return e
+	})
+	if err != nil {
+		return halt(state, err, "Failed to create route rule")
+	}
+	s.routeRuleId = createRouteResult.RouteRuleId
+	ui.Message(fmt.Sprintf("Success to route %s to the internet through nat gateway(%s)", subnetCidr, s.natId))
+
+	return multistep.ActionContinue
+}
+
+func (s *stepConfigNatGateway) Cleanup(state multistep.StateBag) {
+	if !s.Enabled || s.eip == "" {
+		return
+	}
+
+	cleanUpMessage(state, "nat gateway")
+
+	vpcClient := state.Get("vpc_client").(*vpc.Client)
+	eipClient := state.Get("eip_client").(*eip.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+	ctx := context.TODO()
+
+	if s.routeRuleId != "" {
+		err := Retry(ctx, func(ctx context.Context) error {
+			return vpcClient.DeleteRouteRule(s.routeRuleId, uuid.TimeOrderedUUID())
+		})
+		if err != nil {
+			ui.Error(fmt.Sprintf("Failed to delete route rule(%s), please clean it manually: %s", s.routeRuleId, err))
+		}
+	}
+
+	if s.snatRuleId != "" {
+		err := Retry(ctx, func(ctx context.Context) error {
+			return vpcClient.DeleteNatGatewaySnatRule(s.natId, s.snatRuleId, uuid.TimeOrderedUUID())
+		})
+		if err != nil {
+			ui.Error(fmt.Sprintf("Failed to delete snat rule(%s), please clean it manually: %s", s.snatRuleId, err))
+		}
+	}
+
+	if s.natId != "" {
+		err := RetryInUse(ctx, func(ctx context.Context) error {
+			return vpcClient.DeleteNatGateway(s.natId, uuid.TimeOrderedUUID())
+		})
+		if err == nil {
+			err = waitForNatGatewayDeleted(ctx, vpcClient, s.vpcId, s.natId, 600)
+		}
+		if err != nil {
+			ui.Error(fmt.Sprintf("Failed to delete nat gateway(%s), please clean it manually: %s", s.natId, err))
+			return
+		}
+	}
+
+	// the eip is unbound after the nat gateway is deleted
+	err := waitForEip(ctx, eipClient, s.eip, eipStatusAvailable, 300)
+	if err == nil {
+		err = RetryInUse(ctx, func(ctx context.Context) error {
+			return eipClient.DeleteEip(s.eip, uuid.TimeOrderedUUID())
+		})
+	}
+	if err != nil {
+		ui.Error(fmt.Sprintf("Failed to delete eip(%s), please clean it manually: %s", s.eip, err))
+	}
+}
+
+// waitForEip waits for the eip reaching the target status
+func waitForEip(ctx context.Context, client *eip.Client, address, targetStatus string, timeout int) error {
+	for {
+		var listResult *eip.ListEipResult
+		err := Retry(ctx, func(ctx context.Context) error {
+			var e error
+			listResult, e = client.ListEip(&eip.ListEipArgs{Eip: address})
+			return e
+		})
+		if err != nil {
+			return err
+		}
+		if len(listResult.EipList) > 0 && listResult.EipList[0].Status == targetStatus {
+			return nil
+		}
+		time.Sleep(DefaultWaitForInterval * time.Second)
+		timeout = timeout - DefaultWaitForInterval
+		if timeout <= 0 {
+			return fmt.Errorf("wait eip(%s) status(%s) timeout", address, targetStatus)
+		}
+	}
+}
+
+// waitForNatGateway waits for the nat gateway reaching the target status
+func waitForNatGateway(ctx context.Context, client *vpc.Client, natId string, targetStatus vpc.NatStatusType, timeout int) error {
+	for {
+		var nat *vpc.NAT
+		err := Retry(ctx, func(ctx context.Context) error {
+			var e error
+			nat, e = client.GetNatGatewayDetail(natId)
+			return e
+		})
+		if err != nil {
+			return err
+		}
+		if nat.Status == targetStatus {
+			return nil
+		}
+		time.Sleep(DefaultWaitForInterval * time.Second)
+		timeout = timeout - DefaultWaitForInterval
+		if timeout <= 0 {
+			return fmt.Errorf("wait nat gateway(%s) status(%s) timeout", natId, targetStatus)
+		}
+	}
+}
+
+// waitForNatGatewayDeleted waits until the nat gateway is gone
+func waitForNatGatewayDeleted(ctx context.Context, client *vpc.Client, vpcId, natId string, timeout int) error {
+	for {
+		var listResult *vpc.ListNatGatewayResult
+		err := Retry(ctx, func(ctx context.Context) error {
+			var e error
+			listResult, e = client.ListNatGateway(&vpc.ListNatGatewayArgs{VpcId: vpcId, NatId: natId})
+			return e
+		})
+		if err != nil {
+			return err
+		}
+		if len(listResult.Nats) == 0 || listResult.Nats[0].Status == vpc.NAT_STATUS_DELETED {
+			return nil
+		}
+		time.Sleep(DefaultWaitForInterval * time.Second)
+		timeout = timeout - DefaultWaitForInterval
+		if timeout <= 0 {
+			return fmt.Errorf("wait nat gateway(%s) deleted timeout", natId)
+		}
+	}
+}
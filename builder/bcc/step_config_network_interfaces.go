@@ -0,0 +1,203 @@
+package bcc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/baidubce/bce-sdk-go/services/eni"
+	"github.com/baidubce/bce-sdk-go/services/vpc"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
+)
+
+// the status of elastic network interface
+const (
+	eniStatusAvailable = "available"
+	eniStatusInuse     = "inuse"
+)
+
+// stepConfigNetworkInterfaces creates the secondary network interfaces and
+// attaches them to the build instance in order
+type stepConfigNetworkInterfaces struct {
+	Interfaces []NetworkInterfaceConfig
+	Name       string
+	eniIds     []string
+}
+
+func (s *stepConfigNetworkInterfaces) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	if len(s.Interfaces) == 0 {
+		return multistep.ActionContinue
+	}
+
+	eniClient := state.Get("eni_client").(*eni.Client)
+	vpcClient := state.Get("vpc_client").(*vpc.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+	instanceId := state.Get("instance_id").(string)
+	zoneName := state.Get("zone").(string)
+
+	for i, config := range s.Interfaces {
+		ui.Say(fmt.Sprintf("Starting to create network interface %d...", i+1))
+		args := &eni.CreateEniArgs{
+			ClientToken: uuid.TimeOrderedUUID(),
+			Name:        fmt.Sprintf("%s-%d", s.Name, i+1),
+			SubnetId:    config.SubnetId,
+			Description: "network interface for packer",
+		}
+		if args.SubnetId == "" {
+			args.SubnetId = state.Get("subnet_id").(string)
+		} else {
+			// the instance may be launched in a fallback zone
+			subnetDetail, err := vpcClient.GetSubnetDetail(args.SubnetId)
+			if err != nil {
+				return halt(state, err, fmt.Sprintf("Failed to get subnet(%s)", args.SubnetId))
+			}
+			if subnetDetail.Subnet.ZoneName != zoneName {
+				return halt(state, fmt.Errorf("the subnet(%s) is in zone %s, but the instance is in zone %s",
+					args.SubnetId, subnetDetail.Subnet.ZoneName, zoneName), "")
+			}
+		}
+		if len(config.SecurityGroupIds) != 0 {
+			args.SecurityGroupIds = config.SecurityGroupIds
+		} else if ids, enterprise := instanceSecurityGroups(state); enterprise {
+			args.EnterpriseSecurityGroupIds = ids
+		} else {
+			args.SecurityGroupIds = ids
+		}
+		args.PrivateIpSet = []eni.PrivateIp{{Primary: true}}
+		for j := 0; j < config.SecondaryPrivateIpCount; j++ {
+			args.PrivateIpSet = append(args.PrivateIpSet, eni.PrivateIp{Primary: false})
+		}
+
+		var createResult *eni.CreateEniResult
+		err := Retry(ctx, func(ctx context.Context) error {
+			var e error
+			createResult, e = eniClient.CreateEni(args)
+			return e
+		})
+		if err != nil {
+			return halt(state, err, "Failed to create network interface")
+		}
+		eniId := createResult.EniId
+		s.eniIds = append(s.eniIds, eniId)
+		if err := waitForEni(ctx, eniClient, eniId, eniStatusAvailable, 300); err != nil {
+			return halt(state, err, fmt.Sprintf("Failed to wait for network interface(%s) available", eniId))
+		}
+
+		err = Retry(ctx, func(ctx context.Context) error {
+			return eniClient.AttachEniInstance(&eni.EniInstance{
+				EniId:       eniId,
+				InstanceId:  instanceId,
+				ClientToken: uuid.TimeOrderedUUID(),
+			})
+		})
+		if err != nil {
+			return halt(state, err, fmt.Sprintf("Failed to attach network interface(%s)", eniId))
+		}
+		if err := waitForEni(ctx, eniClient, eniId, eniStatusInuse, 300); err != nil {
+			return halt(state, err, fmt.Sprintf("Failed to wait for network interface(%s) attached", eniId))
+		}
+		ui.Message(fmt.Sprintf("Success to attach network interface %s in subnet %s", eniId, args.SubnetId))
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepConfigNetworkInterfaces) Cleanup(state multistep.StateBag) {
+	if len(s.eniIds) == 0 {
+		return
+	}
+
+	cleanUpMessage(state, "network interfaces")
+
+	ui := state.Get("ui").(packersdk.Ui)
+	if err := s.release(context.TODO(), state); err != nil {
+		ui.Error(fmt.Sprintf("%s, please clean it manually", err))
+	}
+}
+
+// release detaches and deletes the network interfaces, it's safe to be
+// called more than once
+func (s *stepConfigNetworkInterfaces) release(ctx context.Context, state multistep.StateBag) error {
+	eniClient := state.Get("eni_client").(*eni.Client)
+	instanceId := state.Get("instance_id").(string)
+
+	for len(s.eniIds) > 0 {
+		eniId := s.eniIds[len(s.eniIds)-1]
+		detail, err := eniClient.GetEniDetail(eniId)
+		if err != nil {
+			return fmt.Errorf("Failed to get network interface(%s): %s", eniId, err)
+		}
+		if detail.Status != eniStatusAvailable {
+			err := Retry(ctx, func(ctx context.Context) error {
+				return eniClient.DetachEniInstance(&eni.EniInstance{
+					EniId:       eniId,
+					InstanceId:  instanceId,
+					ClientToken: uuid.TimeOrderedUUID(),
+				})
+			})
+			if err == nil {
+				err = waitForEni(ctx, eniClient, eniId, eniStatusAvailable, 300)
+			}
+			if err != nil {
+				return fmt.Errorf("Failed to detach network interface(%s): %s", eniId, err)
+			}
+		}
+		err = RetryInUse(ctx, func(ctx context.Context) error {
+			return eniClient.DeleteEni(&eni.DeleteEniArgs{
+				EniId:       eniId,
+				ClientToken: uuid.TimeOrderedUUID(),
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to delete network interface(%s): %s", eniId, err)
+		}
+		s.eniIds = s.eniIds[:len(s.eniIds)-1]
+	}
+	return nil
+}
+
+// stepReleaseNetworkInterfaces detaches and deletes the network interfaces
+// before the image is created, so the image isn't tied to them
+type stepReleaseNetworkInterfaces struct {
+	NetworkInterfaces *stepConfigNetworkInterfaces
+}
+
+func (s *stepReleaseNetworkInterfaces) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	if len(s.NetworkInterfaces.eniIds) == 0 {
+		return multistep.ActionContinue
+	}
+
+	ui := state.Get("ui").(packersdk.Ui)
+	ui.Say("Detaching and deleting network interfaces...")
+	if err := s.NetworkInterfaces.release(ctx, state); err != nil {
+		return halt(state, err, "")
+	}
+	return multistep.ActionContinue
+}
+
+func (s *stepReleaseNetworkInterfaces) Cleanup(state multistep.StateBag) {}
+
+// waitForEni waits for the network interface reaching the target status
+func waitForEni(ctx context.Context, client *eni.Client, eniId, targetStatus string, timeout int) error {
+	for {
+		var detail *eni.Eni
+		err := Retry(ctx, func(ctx context.Context) error {
+			var e error
+			detail, e = client.GetEniDetail(eniId)
+			return e
+		})
+		if err != nil {
+			return err
+		}
+		if detail.Status == targetStatus {
+			return nil
+		}
+		time.Sleep(DefaultWaitForInterval * time.Second)
+		timeout = timeout - DefaultWaitForInterval
+		if timeout <= 0 {
+			return fmt.Errorf("wait network interface(%s) status(%s) timeout", eniId, targetStatus)
+		}
+	}
+}
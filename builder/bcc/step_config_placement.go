@@ -0,0 +1,145 @@
+package bcc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/baidubce/bce-sdk-go/services/bcc"
+	"github.com/baidubce/bce-sdk-go/services/bcc/api"
+	"github.com/baidubce/bce-sdk-go/services/dcc"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
+)
+
+// stepConfigPlacement is used to check the dedicated host and the deployment
+// set the instance will be launched on, or create a temporary deployment set
+type stepConfigPlacement struct {
+	DeploymentSetId         string
+	AutoCreateDeploymentSet bool
+	DedicatedHostId         string
+	// The subnet of the instance, whose zone is resolved from the config or
+	// the existing subnet
+	Subnet      *stepConfigSubnet
+	Description string
+	isCreate    bool
+}
+
+func (s *stepConfigPlacement) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*bcc.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	if len(s.DedicatedHostId) != 0 || len(s.DeploymentSetId) != 0 {
+		ui.Say("Trying to check placement...")
+		if err := checkPlacement(state, s.DedicatedHostId, s.DeploymentSetId, s.zones(state)); err != nil {
+			return halt(state, err, "Failed to check placement")
+		}
+	}
+	if len(s.DeploymentSetId) != 0 {
+		state.Put("deployment_set_id", s.DeploymentSetId)
+		return multistep.ActionContinue
+	}
+
+	if !s.AutoCreateDeploymentSet {
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Starting to create deployment set...")
+
+	var createResult *api.CreateDeploySetResult
+	args := &api.CreateDeploySetArgs{
+		ClientToken: uuid.TimeOrderedUUID(),
+		Strategy:    "HOST_HA",
+		Name:        fmt.Sprintf("packer_%s", uuid.TimeOrderedUUID()[:8]),
+		Desc:        s.Description,
+	}
+	err := Retry(ctx, func(ctx context.Context) error {
+		var e error
+		createResult, e = client.CreateDeploySet(args)
+		return e
+	})
+	if err != nil {
+		return halt(state, err, "Failed to create deployment set")
+	}
+
+	s.DeploymentSetId = createResult.DeploySetId
+	s.isCreate = true
+	state.Put("deployment_set_id", s.DeploymentSetId)
+	ui.Message(fmt.Sprintf("Success to create deployment set: %s", s.DeploymentSetId))
+
+	return multistep.ActionContinue
+}
+
+func (s *stepConfigPlacement) Cleanup(state multistep.StateBag) {
+	if !s.isCreate {
+		return
+	}
+
+	cleanUpMessage(state, "deployment set")
+
+	client := state.Get("client").(*bcc.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+	ctx := context.TODO()
+
+	err := RetryInUse(ctx, func(ctx context.Context) error {
+		return client.DeleteDeploySet(s.DeploymentSetId)
+	})
+	if err != nil {
+		ui.Error(fmt.Sprintf("Failed to delete deployment set(%s), please delete it manually: %s", s.DeploymentSetId, err))
+	}
+}
+
+// zones returns the zones the instance may be launched in: the zone of the
+// subnet if it's specified by user, or else every candidate zone, since the
+// temporary subnet is moved along with the instance
+func (s *stepConfigPlacement) zones(state multistep.StateBag) []string {
+	if !s.Subnet.isCreate && !s.Subnet.UseDefaultNetwork {
+		return []string{s.Subnet.ZoneName}
+	}
+
+	var zones []string
+	for _, zone := range state.Get("config").(*Config).candidateZones() {
+		if zone != "" {
+			zones = append(zones, zone)
+		}
+	}
+	if len(zones) == 0 && s.Subnet.ZoneName != "" {
+		zones = append(zones, s.Subnet.ZoneName)
+	}
+	return zones
+}
+
+// checkPlacement checks that the dedicated host is in the zones, and the
+// deployment set isn't full in any of them. These checks need to query the
+// api, so they can't be done by Prepare, and are done before launching the
+// instance and by the dry run instead. An empty zones skips the zone checks.
+func checkPlacement(state multistep.StateBag, dedicatedHostId, deploymentSetId string, zones []string) error {
+	if dedicatedHostId != "" {
+		dccClient := state.Get("dcc_client").(*dcc.Client)
+		hostDetail, err := dccClient.GetDedicatedHostDetail(dedicatedHostId)
+		if err != nil {
+			return fmt.Errorf("failed to get dedicated host(%s): %w", dedicatedHostId, err)
+		}
+		for _, zoneName := range zones {
+			if hostDetail.DedicatedHost.ZoneName != zoneName {
+				return fmt.Errorf("the dedicated host(%s) is in zone(%s), not in zone(%s)",
+					dedicatedHostId, hostDetail.DedicatedHost.ZoneName, zoneName)
+			}
+		}
+	}
+
+	if deploymentSetId != "" {
+		client := state.Get("client").(*bcc.Client)
+		deploySet, err := client.GetDeploySet(deploymentSetId)
+		if err != nil {
+			return fmt.Errorf("failed to get deployment set(%s): %w", deploymentSetId, err)
+		}
+		for _, zone := range deploySet.InstanceList {
+			if containsString(zones, zone.ZoneName) && zone.Total > 0 && zone.Count >= zone.Total {
+				return fmt.Errorf("the deployment set(%s) is full in zone(%s), %d of %d instances",
+					deploymentSetId, zone.ZoneName, zone.Count, zone.Total)
+			}
+		}
+	}
+	return nil
+}
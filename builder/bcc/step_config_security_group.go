@@ -3,6 +3,7 @@ package bcc
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/baidubce/bce-sdk-go/services/bcc"
 	"github.com/baidubce/bce-sdk-go/services/bcc/api"
@@ -14,10 +15,14 @@ import (
 type stepConfigSecurityGroup struct {
 	UseDefaultNetwork bool
 	SecurityGroupId   string
+	Filter            NetworkFilter
 	SecurityGroupName string
 	Description       string
 	VpcId             string
-	isCreate          bool
+	// the additional security groups to attach
+	SecurityGroupIds           []string
+	EnterpriseSecurityGroupIds []string
+	isCreate                   bool
 }
 
 func (s *stepConfigSecurityGroup) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -29,24 +34,42 @@ func (s *stepConfigSecurityGroup) Run(ctx context.Context, state multistep.State
 	ui := state.Get("ui").(packersdk.Ui)
 	s.VpcId = state.Get("vpc_id").(string)
 
-	if len(s.SecurityGroupId) != 0 {
-		// todo check security group
-		ui.Say(fmt.Sprintf("Trying to check security group(%s)...", s.SecurityGroupId))
-		listResult, err := client.ListSecurityGroup(s.getListSecurityGroupArgs())
-		if err != nil {
-			return halt(state, err, "Failed to list security group")
+	if len(s.EnterpriseSecurityGroupIds) != 0 {
+		ui.Say(fmt.Sprintf("Trying to check enterprise security groups(%s)...", strings.Join(s.EnterpriseSecurityGroupIds, ", ")))
+		if err := checkEnterpriseSecurityGroups(ctx, client, s.EnterpriseSecurityGroupIds); err != nil {
+			return halt(state, err, "Failed to check enterprise security groups")
 		}
+		state.Put("security_group_id", "")
+		state.Put("enterprise_security_group_ids", s.EnterpriseSecurityGroupIds)
+		return multistep.ActionContinue
+	}
 
-		for _, securityGroupItem := range listResult.SecurityGroups {
-			if securityGroupItem.Id == s.SecurityGroupId {
-				state.Put("security_group_id", s.SecurityGroupId)
-				s.isCreate = false
-				return multistep.ActionContinue
-			}
+	if len(s.SecurityGroupIds) != 0 {
+		ui.Say(fmt.Sprintf("Trying to check security groups(%s)...", strings.Join(s.SecurityGroupIds, ", ")))
+		if err := checkSecurityGroups(ctx, client, s.VpcId, s.SecurityGroupIds...); err != nil {
+			return halt(state, err, "Failed to check security groups")
 		}
+		state.Put("security_group_ids", s.SecurityGroupIds)
+	}
 
+	if !s.Filter.Empty() {
+		ui.Say("Looking up security group by filter...")
+		securityGroupId, err := findSecurityGroup(ctx, client, s.VpcId, &s.Filter)
+		if err != nil {
+			return halt(state, err, "Failed to look up security group")
+		}
+		ui.Message(fmt.Sprintf("Found security group: %s", securityGroupId))
+		s.SecurityGroupId = securityGroupId
+	}
+
+	if len(s.SecurityGroupId) != 0 {
+		ui.Say(fmt.Sprintf("Trying to check security group(%s)...", s.SecurityGroupId))
+		if err := checkSecurityGroups(ctx, client, s.VpcId, s.SecurityGroupId); err != nil {
+			return halt(state, err, "Failed to check security group")
+		}
+		state.Put("security_group_id", s.SecurityGroupId)
 		s.isCreate = false
-		return halt(state, fmt.Errorf("The specified security group(%s) doesn't exist", s.SecurityGroupId), "")
+		return multistep.ActionContinue
 	}
 
 	// create security group
@@ -83,18 +106,72 @@ func (s *stepConfigSecurityGroup) Cleanup(state multistep.StateBag) {
 	ui := state.Get("ui").(packersdk.Ui)
 	ctx := context.TODO()
 
-	err := Retry(ctx, func(ctx context.Context) error {
+	err := RetryInUse(ctx, func(ctx context.Context) error {
 		return client.DeleteSecurityGroup(s.SecurityGroupId)
 	})
 	if err != nil {
-		ui.Error(fmt.Sprintf("Failed to delete security group(%s), you can delete it manually: %s", s.SecurityGroupId, err))
+		ui.Error(fmt.Sprintf("Failed to delete security group(%s), you can delete it manually: %s", s.SecurityGroupId, ClassifyError(err)))
+	}
+}
+
+// listSecurityGroups lists all the security groups in the vpc page by page,
+// or all the security groups of the region if vpcId is empty
+func listSecurityGroups(ctx context.Context, client *bcc.Client, vpcId string) ([]api.SecurityGroupModel, error) {
+	var securityGroups []api.SecurityGroupModel
+	args := &api.ListSecurityGroupArgs{
+		VpcId:   vpcId,
+		MaxKeys: 1000,
+	}
+	for {
+		var listResult *api.ListSecurityGroupResult
+		err := Retry(ctx, func(ctx context.Context) error {
+			var e error
+			listResult, e = client.ListSecurityGroup(args)
+			return e
+		})
+		if err != nil {
+			return nil, err
+		}
+		securityGroups = append(securityGroups, listResult.SecurityGroups...)
+		if !listResult.IsTruncated {
+			break
+		}
+		args.Marker = listResult.NextMarker
 	}
+	return securityGroups, nil
 }
 
-func (s *stepConfigSecurityGroup) getListSecurityGroupArgs() *api.ListSecurityGroupArgs {
-	return &api.ListSecurityGroupArgs{
-		VpcId: s.VpcId,
+// checkSecurityGroups checks that the security groups exist in the vpc
+func checkSecurityGroups(ctx context.Context, client *bcc.Client, vpcId string, securityGroupIds ...string) error {
+	securityGroups, err := listSecurityGroups(ctx, client, vpcId)
+	if err != nil {
+		return err
+	}
+	inVpc := map[string]bool{}
+	for _, securityGroup := range securityGroups {
+		inVpc[securityGroup.Id] = true
+	}
+	var missing []string
+	for _, securityGroupId := range securityGroupIds {
+		if !inVpc[securityGroupId] {
+			missing = append(missing, securityGroupId)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	// tell whether it's in another vpc
+	securityGroups, err = listSecurityGroups(ctx, client, "")
+	if err != nil {
+		return err
+	}
+	for _, securityGroup := range securityGroups {
+		if securityGroup.Id == missing[0] {
+			return fmt.Errorf("The security group(%s) belongs to vpc(%s), not vpc(%s)", missing[0], securityGroup.VpcId, vpcId)
+		}
 	}
+	return fmt.Errorf("The specified security group(%s) doesn't exist", missing[0])
 }
 
 func (s *stepConfigSecurityGroup) getCreateSecurityGroupArgs() *api.CreateSecurityGroupArgs {
@@ -115,3 +192,35 @@ func (s *stepConfigSecurityGroup) getCreateSecurityGroupArgs() *api.CreateSecuri
 		},
 	}
 }
+
+// checkEnterpriseSecurityGroups checks that the enterprise security groups
+// exist. They aren't bound to any vpc.
+func checkEnterpriseSecurityGroups(ctx context.Context, client *bcc.Client, securityGroupIds []string) error {
+	found := map[string]bool{}
+	marker := ""
+	for {
+		var listResult *listEnterpriseSecurityGroupsResult
+		err := Retry(ctx, func(ctx context.Context) error {
+			var e error
+			listResult, e = listEnterpriseSecurityGroups(client, marker)
+			return e
+		})
+		if err != nil {
+			return err
+		}
+		for _, securityGroup := range listResult.EnterpriseSecurityGroups {
+			found[securityGroup.Id] = true
+		}
+		if !listResult.IsTruncated {
+			break
+		}
+		marker = listResult.NextMarker
+	}
+
+	for _, securityGroupId := range securityGroupIds {
+		if !found[securityGroupId] {
+			return fmt.Errorf("The specified enterprise security group(%s) doesn't exist", securityGroupId)
+		}
+	}
+	return nil
+}
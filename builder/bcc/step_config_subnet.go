@@ -3,6 +3,7 @@ package bcc
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/baidubce/bce-sdk-go/services/vpc"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
@@ -13,10 +14,14 @@ import (
 type stepConfigSubnet struct {
 	UseDefaultNetwork bool
 	SubnetId          string
+	Filter            NetworkFilter
 	SubnetCidrBlock   string
+	PrefixLength      int
+	AvoidCidrs        []string
 	SubnetName        string
 	ZoneName          string
 	Description       string
+	EnableIpv6        bool
 	isCreate          bool
 }
 
@@ -28,12 +33,34 @@ func (s *stepConfigSubnet) Run(ctx context.Context, state multistep.StateBag) mu
 	client := state.Get("vpc_client").(*vpc.Client)
 	ui := state.Get("ui").(packersdk.Ui)
 
+	if !s.Filter.Empty() {
+		ui.Say("Looking up subnet by filter...")
+		var zones []string
+		if s.ZoneName != "" {
+			zones = state.Get("config").(*Config).candidateZones()
+		}
+		subnetId, err := findSubnet(ctx, client, state.Get("vpc_id").(string), &s.Filter, zones)
+		if err != nil {
+			return halt(state, err, "Failed to look up subnet")
+		}
+		ui.Message(fmt.Sprintf("Found subnet: %s", subnetId))
+		s.SubnetId = subnetId
+	}
+
 	if len(s.SubnetId) != 0 {
 		ui.Say(fmt.Sprintf("Trying to check existing subnet(%s)...", s.SubnetId))
 		subnetDetail, err := client.GetSubnetDetail(s.SubnetId)
 		if err != nil {
 			return halt(state, err, fmt.Sprintf("Failed to get subnet(%s), it may not exist: %s", s.SubnetId, err))
 		}
+		if err := s.checkSubnet(state, &subnetDetail.Subnet); err != nil {
+			return halt(state, err, "")
+		}
+		if s.ZoneName == "" {
+			ui.Message(fmt.Sprintf("Using zone(%s) of the subnet", subnetDetail.Subnet.ZoneName))
+		}
+		// the instance can only be launched in the zone of the subnet
+		s.ZoneName = subnetDetail.Subnet.ZoneName
 		state.Put("subnet_id", subnetDetail.Subnet.SubnetId)
 		return multistep.ActionContinue
 	}
@@ -41,19 +68,9 @@ func (s *stepConfigSubnet) Run(ctx context.Context, state multistep.StateBag) mu
 	// create new subnet
 	ui.Say("Starting to create new subnet...")
 
-	var createResult *vpc.CreateSubnetResult
-	err := Retry(ctx, func(ctx context.Context) error {
-		var e error
-		createResult, e = client.CreateSubnet(s.getCreateSubnetArgs(state))
-		return e
-	})
-	if err != nil {
+	if err := s.createSubnet(ctx, state); err != nil {
 		return halt(state, err, "Failed to create subnet")
 	}
-
-	s.isCreate = true
-	s.SubnetId = createResult.SubnetId
-	state.Put("subnet_id", s.SubnetId)
 	ui.Message(fmt.Sprintf("Success to create subnet: %s", s.SubnetId))
 
 	return multistep.ActionContinue
@@ -64,30 +81,126 @@ func (s *stepConfigSubnet) Cleanup(state multistep.StateBag) {
 		return
 	}
 
-	client := state.Get("vpc_client").(*vpc.Client)
 	ui := state.Get("ui").(packersdk.Ui)
 	ctx := context.TODO()
 
 	cleanUpMessage(state, "subnet")
 
+	if err := s.deleteSubnet(ctx, state); err != nil {
+		ui.Error(fmt.Sprintf("Failed to delete subnet(%s), please clean it manully: %s", s.SubnetId, ClassifyError(err)))
+	}
+}
+
+// checkSubnet checks that the specified subnet belongs to the vpc, is in one
+// of the candidate zones, and has free ips
+func (s *stepConfigSubnet) checkSubnet(state multistep.StateBag, subnet *vpc.Subnet) error {
+	config := state.Get("config").(*Config)
+	vpcId := state.Get("vpc_id").(string)
+
+	if subnet.VPCId != vpcId {
+		return fmt.Errorf("The subnet(%s) belongs to vpc(%s), not vpc(%s)", subnet.SubnetId, subnet.VPCId, vpcId)
+	}
+	if zones := config.candidateZones(); s.ZoneName != "" && !containsString(zones, subnet.ZoneName) {
+		return fmt.Errorf("The subnet(%s) is in zone(%s), not in zone(%s)", subnet.SubnetId, subnet.ZoneName, strings.Join(zones, ", "))
+	}
+	if subnet.AvailableIp < 1 {
+		return fmt.Errorf("The subnet(%s) has no free ip", subnet.SubnetId)
+	}
+	return nil
+}
+
+// switchZone moves the temporary subnet to the specified zone by recreating
+// it. It returns false if the subnet is specified by user, which can't be
+// moved to another zone.
+func (s *stepConfigSubnet) switchZone(ctx context.Context, state multistep.StateBag, zoneName string) (bool, error) {
+	if s.UseDefaultNetwork || zoneName == s.ZoneName {
+		s.ZoneName = zoneName
+		return true, nil
+	}
+	if !s.isCreate {
+		return false, nil
+	}
+
+	ui := state.Get("ui").(packersdk.Ui)
+	ui.Say(fmt.Sprintf("Recreating subnet in zone(%s)...", zoneName))
+
+	if err := s.deleteSubnet(ctx, state); err != nil {
+		return false, fmt.Errorf("Failed to delete subnet(%s): %w", s.SubnetId, err)
+	}
+	s.isCreate = false
+
+	s.ZoneName = zoneName
+	if err := s.createSubnet(ctx, state); err != nil {
+		return false, fmt.Errorf("Failed to create subnet: %w", err)
+	}
+	ui.Message(fmt.Sprintf("Success to create subnet: %s", s.SubnetId))
+
+	return true, nil
+}
+
+func (s *stepConfigSubnet) createSubnet(ctx context.Context, state multistep.StateBag) error {
+	client := state.Get("vpc_client").(*vpc.Client)
+
+	if s.SubnetCidrBlock == cidrAuto {
+		cidr, err := s.pickCidr(state)
+		if err != nil {
+			return fmt.Errorf("Failed to pick cidr block: %w", err)
+		}
+		ui := state.Get("ui").(packersdk.Ui)
+		ui.Message(fmt.Sprintf("Using cidr block %s for subnet", cidr))
+		s.SubnetCidrBlock = cidr
+	}
+
+	var createResult *vpc.CreateSubnetResult
 	err := Retry(ctx, func(ctx context.Context) error {
-		return client.DeleteSubnet(s.SubnetId, uuid.TimeOrderedUUID())
+		var e error
+		createResult, e = createSubnet(client, s.getCreateSubnetArgs(state))
+		return e
 	})
 	if err != nil {
-		ui.Error(fmt.Sprintf("Failed to delete subnet(%s), please clean it manully: %s", s.SubnetId, err))
+		return err
 	}
+
+	s.isCreate = true
+	s.SubnetId = createResult.SubnetId
+	state.Put("subnet_id", s.SubnetId)
+	return nil
+}
+
+// pickCidr picks the first free block in the vpc, which overlaps neither
+// the existing subnets nor `avoid_cidrs`
+func (s *stepConfigSubnet) pickCidr(state multistep.StateBag) (string, error) {
+	client := state.Get("vpc_client").(*vpc.Client)
+	vpcId := state.Get("vpc_id").(string)
+
+	vpcDetail, err := client.GetVPCDetail(vpcId)
+	if err != nil {
+		return "", err
+	}
+	return pickFreeSubnetCidr(&vpcDetail.VPC, s.PrefixLength, s.AvoidCidrs)
+}
+
+func (s *stepConfigSubnet) deleteSubnet(ctx context.Context, state multistep.StateBag) error {
+	client := state.Get("vpc_client").(*vpc.Client)
+
+	return RetryInUse(ctx, func(ctx context.Context) error {
+		return client.DeleteSubnet(s.SubnetId, uuid.TimeOrderedUUID())
+	})
 }
 
-func (s *stepConfigSubnet) getCreateSubnetArgs(state multistep.StateBag) *vpc.CreateSubnetArgs {
+func (s *stepConfigSubnet) getCreateSubnetArgs(state multistep.StateBag) *createSubnetArgs {
 	vpcId := state.Get("vpc_id").(string)
 
-	return &vpc.CreateSubnetArgs{
-		ClientToken: uuid.TimeOrderedUUID(),
-		SubnetType:  vpc.SUBNET_TYPE_BCC,
-		Name:        s.SubnetName,
-		Cidr:        s.SubnetCidrBlock,
-		Description: s.Description,
-		VpcId:       vpcId,
-		ZoneName:    s.ZoneName,
+	return &createSubnetArgs{
+		CreateSubnetArgs: &vpc.CreateSubnetArgs{
+			ClientToken: uuid.TimeOrderedUUID(),
+			SubnetType:  vpc.SUBNET_TYPE_BCC,
+			Name:        s.SubnetName,
+			Cidr:        s.SubnetCidrBlock,
+			Description: s.Description,
+			VpcId:       vpcId,
+			ZoneName:    s.ZoneName,
+		},
+		EnableIpv6: s.EnableIpv6,
 	}
 }
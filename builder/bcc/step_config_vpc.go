@@ -13,9 +13,11 @@ import (
 type stepConfigVPC struct {
 	UseDefaultNetwork bool
 	VpcId             string
+	Filter            NetworkFilter
 	CidrBlock         string
 	VpcName           string
 	Description       string
+	EnableIpv6        bool
 	isCreate          bool
 }
 
@@ -27,6 +29,16 @@ func (s *stepConfigVPC) Run(ctx context.Context, state multistep.StateBag) multi
 	client := state.Get("vpc_client").(*vpc.Client)
 	ui := state.Get("ui").(packersdk.Ui)
 
+	if !s.Filter.Empty() {
+		ui.Say("Looking up vpc by filter...")
+		vpcId, err := findVpc(ctx, client, &s.Filter)
+		if err != nil {
+			return halt(state, err, "Failed to look up vpc")
+		}
+		ui.Message(fmt.Sprintf("Found vpc: %s", vpcId))
+		s.VpcId = vpcId
+	}
+
 	if len(s.VpcId) != 0 {
 		// check whether the specified vpi id exists
 		ui.Say(fmt.Sprintf("Trying to check existing VPC(%s)...", s.VpcId))
@@ -46,7 +58,7 @@ func (s *stepConfigVPC) Run(ctx context.Context, state multistep.StateBag) multi
 	var createResult *vpc.CreateVPCResult
 	err := Retry(ctx, func(ctx context.Context) error {
 		var e error
-		createResult, e = client.CreateVPC(s.getCreateVpcArgs())
+		createResult, e = createVPC(client, s.getCreateVpcArgs())
 		return e
 	})
 	if err != nil {
@@ -74,19 +86,22 @@ func (s *stepConfigVPC) Cleanup(state multistep.StateBag) {
 	ui := state.Get("ui").(packersdk.Ui)
 	ctx := context.TODO()
 
-	err := Retry(ctx, func(ctx context.Context) error {
+	err := RetryInUse(ctx, func(ctx context.Context) error {
 		return client.DeleteVPC(s.VpcId, uuid.TimeOrderedUUID())
 	})
 	if err != nil {
-		ui.Error(fmt.Sprintf("Failed to delete vpc(%s), please clean it manually: %s", s.VpcId, err))
+		ui.Error(fmt.Sprintf("Failed to delete vpc(%s), please clean it manually: %s", s.VpcId, ClassifyError(err)))
 	}
 }
 
-func (s *stepConfigVPC) getCreateVpcArgs() *vpc.CreateVPCArgs {
-	return &vpc.CreateVPCArgs{
-		ClientToken: uuid.TimeOrderedUUID(),
-		Name:        s.VpcName,
-		Cidr:        s.CidrBlock,
-		Description: s.Description,
+func (s *stepConfigVPC) getCreateVpcArgs() *createVPCArgs {
+	return &createVPCArgs{
+		CreateVPCArgs: &vpc.CreateVPCArgs{
+			ClientToken: uuid.TimeOrderedUUID(),
+			Name:        s.VpcName,
+			Cidr:        s.CidrBlock,
+			Description: s.Description,
+		},
+		EnableIpv6: s.EnableIpv6,
 	}
 }
@@ -14,6 +14,7 @@ import (
 type stepRemoteCopyImage struct {
 	DestinationRegions []string
 	SourceRegion       string
+	EncryptKeyIds      map[string]string
 }
 
 func (s *stepRemoteCopyImage) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -21,41 +22,46 @@ func (s *stepRemoteCopyImage) Run(ctx context.Context, state multistep.StateBag)
 		return multistep.ActionContinue
 	}
 
-	//config := state.Get("config").(*Config)
+	// no image is created if 'skip_create_image' is true
+	rawImageId, ok := state.GetOk("image_id")
+	if !ok {
+		return multistep.ActionContinue
+	}
+
 	client := state.Get("client").(*bcc.Client)
-	imageId := state.Get("image_id").(string)
+	imageId := rawImageId.(string)
 	ui := state.Get("ui").(packersdk.Ui)
 
-	// copy image to remote region
-	remoteCopyImageArgs := s.getRemoteCopyImageArgs(state)
+	// record the mapping of region to image id of custom image made by the build process
+	baiduCloudImages := state.Get("baiducloud_images").(map[string]string)
 
-	ui.Say(fmt.Sprintf("Trying to copy image(%s) to region: %s", imageId, strings.Join(remoteCopyImageArgs.DestRegion, ",")))
+	// copy image to remote region, the regions with their own kms key are
+	// copied one by one
+	for _, remoteCopyImageArgs := range s.getRemoteCopyImageArgs(state) {
+		ui.Say(fmt.Sprintf("Trying to copy image(%s) to region: %s", imageId, strings.Join(remoteCopyImageArgs.DestRegion, ",")))
 
-	var remoteCopyResult *api.RemoteCopyImageResult
-	err := Retry(ctx, func(ctx context.Context) error {
-		var e error
-		remoteCopyResult, e = client.RemoteCopyImageReturnImageIds(imageId, remoteCopyImageArgs)
-		return e
-	})
-	if err != nil {
-		return halt(state, err, "Failed to copy image")
+		var remoteCopyResult *api.RemoteCopyImageResult
+		err := Retry(ctx, func(ctx context.Context) error {
+			var e error
+			remoteCopyResult, e = remoteCopyImage(client, imageId, remoteCopyImageArgs)
+			return e
+		})
+		if err != nil {
+			return halt(state, err, "Failed to copy image")
+		}
+		for _, image := range remoteCopyResult.RemoteCopyImages {
+			baiduCloudImages[image.Region] = image.ImageId
+		}
+		state.Put("baiducloud_images", baiduCloudImages)
 	}
 
 	// waiting image available after remote image copy
 	ui.Say("Waiting for image ready...")
 	if err := WaitForImage(ctx, client, imageId, api.ImageStatusAvailable, 1800); err != nil {
-		panic(err)
+		return halt(state, err, fmt.Sprintf("Failed to wait for image(%s) ready", imageId))
 	}
 	// time.Sleep(10 * time.Second)
 
-	// record the mapping of region to image id of custom image made by the build process
-	baiduCloudImages := state.Get("baiducloud_images").(map[string]string)
-	for _, image := range remoteCopyResult.RemoteCopyImages {
-		baiduCloudImages[image.Region] = image.ImageId
-	}
-
-	state.Put("baiducloud_images", baiduCloudImages)
-
 	return multistep.ActionContinue
 }
 
@@ -63,12 +69,13 @@ func (s *stepRemoteCopyImage) Cleanup(state multistep.StateBag) {
 	_, cancelled := state.GetOk(multistep.StateCancelled)
 	_, halted := state.GetOk(multistep.StateHalted)
 
-	if !cancelled && !halted {
+	rawImages, ok := state.GetOk("baiducloud_images")
+	if !cancelled && !halted || !ok {
 		return
 	}
 
 	config := state.Get("config").(*Config)
-	baiduCloudImages := state.Get("baiducloud_images").(map[string]string)
+	baiduCloudImages := rawImages.(map[string]string)
 	ui := state.Get("ui").(packersdk.Ui)
 
 	ctx := context.TODO()
@@ -112,17 +119,35 @@ func (s *stepRemoteCopyImage) Cleanup(state multistep.StateBag) {
 	}
 }
 
-func (s *stepRemoteCopyImage) getRemoteCopyImageArgs(state multistep.StateBag) *api.RemoteCopyImageArgs {
+func (s *stepRemoteCopyImage) getRemoteCopyImageArgs(state multistep.StateBag) []*remoteCopyImageArgs {
 	config := state.Get("config").(*Config)
 
+	var argsList []*remoteCopyImageArgs
 	var destRegion []string
 	for _, region := range s.DestinationRegions {
-		if region != s.SourceRegion {
-			destRegion = append(destRegion, region)
+		if region == s.SourceRegion {
+			continue
+		}
+		if encryptKeyId, ok := s.EncryptKeyIds[region]; ok {
+			argsList = append(argsList, &remoteCopyImageArgs{
+				RemoteCopyImageArgs: &api.RemoteCopyImageArgs{
+					Name:       config.ImageName,
+					DestRegion: []string{region},
+				},
+				EncryptKey: encryptKeyId,
+			})
+			continue
 		}
+		destRegion = append(destRegion, region)
 	}
-	return &api.RemoteCopyImageArgs{
-		Name:       config.ImageName,
-		DestRegion: destRegion,
+
+	if len(destRegion) > 0 {
+		argsList = append(argsList, &remoteCopyImageArgs{
+			RemoteCopyImageArgs: &api.RemoteCopyImageArgs{
+				Name:       config.ImageName,
+				DestRegion: destRegion,
+			},
+		})
 	}
+	return argsList
 }
@@ -12,6 +12,7 @@ import (
 )
 
 type stepCreateImage struct {
+	Skip    bool
 	imageId string
 }
 
@@ -20,12 +21,17 @@ func (s *stepCreateImage) Run(ctx context.Context, state multistep.StateBag) mul
 	config := state.Get("config").(*Config)
 	ui := state.Get("ui").(packersdk.Ui)
 
+	if s.Skip {
+		ui.Say("Skipping image creation, since 'skip_create_image' is true")
+		return multistep.ActionContinue
+	}
+
 	ui.Say("Starting to create custom image...")
 
 	var createImageResult *api.CreateImageResult
 	err := Retry(ctx, func(ctx context.Context) error {
 		var e error
-		createImageResult, e = client.CreateImage(s.getCreateImageArgs(state))
+		createImageResult, e = createImage(client, s.getCreateImageArgs(state))
 		return e
 	})
 	if err != nil {
@@ -74,13 +80,16 @@ func (s *stepCreateImage) Cleanup(state multistep.StateBag) {
 	}
 }
 
-func (s *stepCreateImage) getCreateImageArgs(state multistep.StateBag) *api.CreateImageArgs {
+func (s *stepCreateImage) getCreateImageArgs(state multistep.StateBag) *createImageArgs {
 	config := state.Get("config").(*Config)
 	instanceId := state.Get("instance_id").(string)
-	return &api.CreateImageArgs{
-		ImageName:  config.ImageName,
-		InstanceId: instanceId,
-		// IsRelateCds: true,
-		ClientToken: uuid.TimeOrderedUUID(),
+	return &createImageArgs{
+		CreateImageArgs: &api.CreateImageArgs{
+			ImageName:  config.ImageName,
+			InstanceId: instanceId,
+			// IsRelateCds: true,
+			ClientToken: uuid.TimeOrderedUUID(),
+		},
+		EncryptKey: config.ImageEncryptKeyId,
 	}
 }
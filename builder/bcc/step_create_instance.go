@@ -3,24 +3,36 @@ package bcc
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/baidubce/bce-sdk-go/bce"
 	"github.com/baidubce/bce-sdk-go/model"
 	"github.com/baidubce/bce-sdk-go/services/bcc"
 	"github.com/baidubce/bce-sdk-go/services/bcc/api"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
 	"github.com/hashicorp/packer-plugin-sdk/uuid"
 )
 
+// spotWatchInterval is the interval of checking whether the spot instance is reclaimed
+const spotWatchInterval = 30 * time.Second
+
 type stepCreateInstance struct {
 	UseDefaultNetwork        bool
 	AssociatePublicIpAddress bool
 	SourceImageId            string
 	InstanceName             string
-	InstanceSpec             string
-	ZoneName                 string
+	InstanceSpecs            []string
+	ZoneNames                []string
+	Subnet                   *stepConfigSubnet
 	InternetChargeType       string
 	SecurityGroupId          string
 	instanceId               string
@@ -28,7 +40,15 @@ type stepCreateInstance struct {
 	NetworkCapacityInMbps    int
 	UserData                 string
 	UserDataFile             string
+	UserDataFiles            []string
 	Tags                     map[string]string
+	SpotStrategy             string
+	SpotPriceLimit           float64
+	SpotFallbackToOnDemand   bool
+	DedicatedHostId          string
+	isSpot                   bool
+	spotReclaimed            int32
+	stopSpotWatcher          context.CancelFunc
 }
 
 func (s *stepCreateInstance) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -36,11 +56,20 @@ func (s *stepCreateInstance) Run(ctx context.Context, state multistep.StateBag)
 	ui := state.Get("ui").(packersdk.Ui)
 
 	ui.Say("Creating instance...")
-	createInstanceBySpecArgs, err := s.getCreateInstanceBySpecArgs(state)
-	if err != nil {
-		return halt(state, err, "Failed to get `CreateInstanceBySpecArgs`")
+
+	var createResult *api.CreateInstanceBySpecResult
+	var err error
+	if s.SpotStrategy != SpotStrategyNone {
+		createResult, err = s.createInstanceInCandidates(ctx, state, true)
+		if err != nil && ErrorKindOf(err) == ErrorKindCapacity && s.SpotFallbackToOnDemand {
+			ui.Message("Failed to create spot instance, falling back to on-demand instance...")
+			createResult, err = s.createInstanceInCandidates(ctx, state, false)
+		} else if err == nil {
+			s.isSpot = true
+		}
+	} else {
+		createResult, err = s.createInstanceInCandidates(ctx, state, false)
 	}
-	createResult, err := client.CreateInstanceBySpec(createInstanceBySpecArgs)
 	if err != nil {
 		return halt(state, err, "Failed to create instance")
 	}
@@ -68,29 +97,92 @@ func (s *stepCreateInstance) Run(ctx context.Context, state multistep.StateBag)
 	state.Put("instance", &instance)
 	s.instanceId = instanceId
 
+	if s.isSpot {
+		state.Put("spot_instance", true)
+		watchCtx, cancel := context.WithCancel(ctx)
+		s.stopSpotWatcher = cancel
+		go s.watchSpotInstance(watchCtx, state)
+	}
+
 	return multistep.ActionContinue
 }
 
+// createInstanceInCandidates tries the instance specs in each zone by
+// priority, until the instance is created or an error other than sold out
+// is returned
+func (s *stepCreateInstance) createInstanceInCandidates(ctx context.Context, state multistep.StateBag, spot bool) (*api.CreateInstanceBySpecResult, error) {
+	ui := state.Get("ui").(packersdk.Ui)
+
+	var err error
+	for _, zoneName := range s.ZoneNames {
+		if zoneName == "" {
+			// the zone may be derived from the specified subnet
+			zoneName = s.Subnet.ZoneName
+		}
+		ok, e := s.Subnet.switchZone(ctx, state, zoneName)
+		if e != nil {
+			return nil, fmt.Errorf("Failed to switch to zone(%s): %w", zoneName, e)
+		}
+		if !ok {
+			ui.Message(fmt.Sprintf("Skip zone(%s), since the specified subnet isn't in it", zoneName))
+			continue
+		}
+
+		for _, instanceSpec := range s.InstanceSpecs {
+			var createResult *api.CreateInstanceBySpecResult
+			createResult, err = s.createInstance(ctx, state, instanceSpec, zoneName, spot)
+			if err == nil {
+				ui.Message(fmt.Sprintf("Using instance spec(%s) in zone(%s)", instanceSpec, zoneName))
+				state.Put("instance_spec", instanceSpec)
+				state.Put("zone", zoneName)
+				return createResult, nil
+			}
+			if ErrorKindOf(err) != ErrorKindCapacity {
+				return nil, err
+			}
+			ui.Message(fmt.Sprintf("Instance spec(%s) is sold out in zone(%s)", instanceSpec, zoneName))
+		}
+	}
+
+	if err == nil {
+		err = fmt.Errorf("No zone is available for the specified subnet")
+	}
+	return nil, err
+}
+
 func (s *stepCreateInstance) Cleanup(state multistep.StateBag) {
 	if len(s.instanceId) == 0 {
 		return
 	}
 
 	ctx := context.TODO()
+	client := state.Get("client").(*bcc.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	if s.isSpot {
+		s.stopSpotWatcher()
+
+		// make it clear that the build failed because of spot reclamation,
+		// unless the watcher has already reported it
+		reported := atomic.LoadInt32(&s.spotReclaimed) == 1
+		_, halted := state.GetOk(multistep.StateHalted)
+		if rawErr, ok := state.GetOk("error"); ok && halted && !reported && s.checkSpotReclaimed(client) {
+			state.Put("error", fmt.Errorf("%s: %w", s.spotReclaimedMessage(), rawErr.(error)))
+		}
+	}
 
 	// clean up message
 	cleanUpMessage(state, "instance and relate resource")
-
-	client := state.Get("client").(*bcc.Client)
-	ui := state.Get("ui").(packersdk.Ui)
 	// instance := state.Get("instance").(*api.InstanceModel)
 
-	err := Retry(ctx, func(ctx context.Context) error {
+	// a reclaimed spot instance may still be in the recycle bin, so it's
+	// deleted as well
+	err := RetryInUse(ctx, func(ctx context.Context) error {
 		return client.DeleteInstanceWithRelateResource(s.instanceId, &api.DeleteInstanceWithRelateResourceArgs{
 			RelatedReleaseFlag: true,
 		})
 	})
-	if err != nil {
+	if err != nil && !isNotFound(err) {
 		ui.Error(fmt.Sprintf("Failed to clean up instance %s: %s", s.instanceId, err))
 	}
 
@@ -108,23 +200,95 @@ func (s *stepCreateInstance) Cleanup(state multistep.StateBag) {
 	// }
 }
 
-func (s *stepCreateInstance) getCreateInstanceBySpecArgs(state multistep.StateBag) (*api.CreateInstanceBySpecArgs, error) {
-	config := state.Get("config").(*Config)
+// watchSpotInstance polls the spot instance until ctx is done, and cancels
+// the build as soon as the instance is reclaimed, instead of waiting for the
+// communicator or provisioners to time out
+func (s *stepCreateInstance) watchSpotInstance(ctx context.Context, state multistep.StateBag) {
+	client := state.Get("client").(*bcc.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+	cancelBuild := state.Get("cancel_build").(context.CancelFunc)
+
+	ticker := time.NewTicker(spotWatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if s.checkSpotReclaimed(client) {
+				err := errors.New(s.spotReclaimedMessage())
+				ui.Error(err.Error())
+				state.Put("error", err)
+				cancelBuild()
+				return
+			}
+		}
+	}
+}
 
-	keypairId := config.KeypairId
-	if len(keypairId) == 0 {
-		// Use temporary keypair
-		if _, ok := state.GetOk("temporary_key_pair_id"); ok {
-			keypairId = state.Get("temporary_key_pair_id").(string)
+// checkSpotReclaimed reports whether the spot instance is released
+func (s *stepCreateInstance) checkSpotReclaimed(client *bcc.Client) bool {
+	if atomic.LoadInt32(&s.spotReclaimed) == 1 {
+		return true
+	}
+
+	reclaimed := false
+	detailResult, err := client.GetInstanceDetail(s.instanceId)
+	if err != nil {
+		reclaimed = isNotFound(err)
+	} else {
+		switch detailResult.Instance.Status {
+		case api.InstanceStatusDeleted, api.InstanceStatusRecycled, api.InstanceStatusExpired:
+			reclaimed = true
 		}
 	}
 
-	password := config.Comm.SSHPassword
-	if password == "" && config.Comm.WinRMPassword != "" {
-		password = config.Comm.WinRMPassword
+	if reclaimed {
+		atomic.StoreInt32(&s.spotReclaimed, 1)
+	}
+	return reclaimed
+}
+
+// spotReclaimedMessage returns the error message of a reclaimed spot
+// instance. 'spot_fallback_to_on_demand' only applies when the instance is
+// created, so it doesn't help against reclamation during the build.
+func (s *stepCreateInstance) spotReclaimedMessage() string {
+	hint := "set 'spot_strategy' to 'none' to build on an on-demand instance"
+	if s.SpotStrategy == SpotStrategyCustomPrice {
+		hint = "raise 'spot_price_limit', or " + hint
+	}
+	return fmt.Sprintf("The spot instance(%s) was reclaimed during the build, please %s", s.instanceId, hint)
+}
+
+// isNotFound reports whether err is a not found error of the service
+func isNotFound(err error) bool {
+	var bceErr *bce.BceServiceError
+	return errors.As(err, &bceErr) && bceErr.StatusCode == http.StatusNotFound
+}
+
+func (s *stepCreateInstance) createInstance(ctx context.Context, state multistep.StateBag, instanceSpec, zoneName string, spot bool) (*api.CreateInstanceBySpecResult, error) {
+	client := state.Get("client").(*bcc.Client)
+
+	args, err := s.getCreateInstanceBySpecArgs(state, instanceSpec, zoneName, spot)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get `CreateInstanceBySpecArgs`: %w", err)
 	}
 
-	userData, err := s.getUserData(state)
+	var createResult *api.CreateInstanceBySpecResult
+	err = Retry(ctx, func(ctx context.Context) error {
+		var e error
+		createResult, e = createInstanceBySpec(client, args)
+		return e
+	})
+	return createResult, err
+}
+
+func (s *stepCreateInstance) getCreateInstanceBySpecArgs(state multistep.StateBag, instanceSpec, zoneName string, spot bool) (*createInstanceBySpecArgs, error) {
+	config := state.Get("config").(*Config)
+
+	keypairId, password := instanceCredentials(state)
+
+	userData, err := s.getUserData(state, zoneName)
 	if err != nil {
 		return nil, err
 	}
@@ -148,10 +312,10 @@ func (s *stepCreateInstance) getCreateInstanceBySpecArgs(state multistep.StateBa
 		Billing: api.Billing{
 			PaymentTiming: api.PaymentTimingPostPaid,
 		},
-		Spec:                s.InstanceSpec,
+		Spec:                instanceSpec,
 		RootDiskSizeInGb:    config.RootDiskSizeInGb,
 		RootDiskStorageType: api.StorageType(config.RootDiskStorageType),
-		ZoneName:            s.ZoneName,
+		ZoneName:            zoneName,
 		PurchaseCount:       1,
 		Name:                s.InstanceName,
 		ClientToken:         uuid.TimeOrderedUUID(),
@@ -168,11 +332,7 @@ func (s *stepCreateInstance) getCreateInstanceBySpecArgs(state multistep.StateBa
 	}
 
 	if !s.UseDefaultNetwork {
-		subnetId := state.Get("subnet_id").(string)
-		securityGroupId := state.Get("security_group_id").(string)
-
-		args.SubnetId = subnetId
-		args.SecurityGroupId = securityGroupId
+		args.SubnetId = state.Get("subnet_id").(string)
 	}
 
 	if s.AssociatePublicIpAddress {
@@ -189,10 +349,34 @@ func (s *stepCreateInstance) getCreateInstanceBySpecArgs(state multistep.StateBa
 		args.RelationTag = true
 	}
 
-	return args, nil
+	if deploySetId, ok := state.GetOk("deployment_set_id"); ok {
+		args.DeployIdList = []string{deploySetId.(string)}
+	}
+
+	extArgs := &createInstanceBySpecArgs{
+		CreateInstanceBySpecArgs: args,
+		DedicatedHostId:          s.DedicatedHostId,
+		RootDiskEncryptKey:       config.RootDiskEncryptKeyId,
+		Ipv6:                     config.EnableIpv6,
+	}
+	if !s.UseDefaultNetwork {
+		setSecurityGroups(state, extArgs)
+	}
+	if spot {
+		args.Billing.PaymentTiming = api.PaymentTimingBidding
+		if s.SpotStrategy == SpotStrategyCustomPrice {
+			extArgs.BidModel = "custom"
+			extArgs.BidPrice = strconv.FormatFloat(s.SpotPriceLimit, 'f', -1, 64)
+		} else {
+			extArgs.BidModel = "market"
+		}
+	}
+
+	return extArgs, nil
 }
 
-func (s *stepCreateInstance) getUserData(state multistep.StateBag) (string, error) {
+func (s *stepCreateInstance) getUserData(state multistep.StateBag, zoneName string) (string, error) {
+	config := state.Get("config").(*Config)
 	userData := s.UserData
 
 	if len(s.UserDataFile) != 0 {
@@ -204,9 +388,100 @@ func (s *stepCreateInstance) getUserData(state multistep.StateBag) (string, erro
 		userData = string(data)
 	}
 
+	if len(s.UserDataFiles) != 0 {
+		ictx := config.ctx
+		ictx.Data = s.getUserDataTemplateData(state, zoneName)
+		data, err := assembleUserData(s.UserDataFiles, func(content string) (string, error) {
+			return interpolate.Render(content, &ictx)
+		})
+		if err != nil {
+			return "", err
+		}
+
+		userData = data
+	}
+
+	if len(userData) == 0 && config.isWindows() {
+		userData = winrmBootstrapUserData
+	}
+
+	if err := checkUserDataSize(userData); err != nil {
+		return "", err
+	}
 	if len(userData) != 0 {
 		userData = base64.StdEncoding.EncodeToString([]byte(userData))
 	}
 
 	return userData, nil
 }
+
+// getUserDataTemplateData returns the data to interpolate `UserDataFiles`
+func (s *stepCreateInstance) getUserDataTemplateData(state multistep.StateBag, zoneName string) map[string]interface{} {
+	config := state.Get("config").(*Config)
+
+	data := map[string]interface{}{}
+	if generatedData, ok := state.GetOk("generated_data"); ok {
+		for k, v := range generatedData.(map[string]interface{}) {
+			data[k] = v
+		}
+	}
+	data["BuildName"] = config.PackerBuildName
+	data["Region"] = config.BaiduCloudRegion
+	data["Zone"] = zoneName
+	data["SSHPublicKey"] = strings.TrimSpace(string(config.Comm.SSHPublicKey))
+	return data
+}
+
+// instanceCredentials returns the keypair and admin password of the build
+// instance
+func instanceCredentials(state multistep.StateBag) (string, string) {
+	config := state.Get("config").(*Config)
+
+	var keypairId string
+	if _, ok := state.GetOk("key_pair_id"); ok {
+		// Use existing keypair, which may be looked up by name
+		keypairId = state.Get("key_pair_id").(string)
+	} else if _, ok := state.GetOk("temporary_key_pair_id"); ok {
+		// Use temporary keypair
+		keypairId = state.Get("temporary_key_pair_id").(string)
+	}
+
+	password := config.Comm.SSHPassword
+	if password == "" && config.Comm.WinRMPassword != "" {
+		password = config.Comm.WinRMPassword
+	}
+	return keypairId, password
+}
+
+// setSecurityGroups sets the security groups configured by
+// stepConfigSecurityGroup to the args of creating instance
+func setSecurityGroups(state multistep.StateBag, args *createInstanceBySpecArgs) {
+	ids, enterprise := instanceSecurityGroups(state)
+	switch {
+	case enterprise:
+		args.EnterpriseSecurityGroupIds = ids
+	case len(ids) == 1:
+		args.SecurityGroupId = ids[0]
+	default:
+		args.SecurityGroupIds = ids
+	}
+}
+
+// instanceSecurityGroups returns the security groups of the build instance,
+// and whether they are enterprise security groups
+func instanceSecurityGroups(state multistep.StateBag) ([]string, bool) {
+	if rawIds, ok := state.GetOk("enterprise_security_group_ids"); ok {
+		return rawIds.([]string), true
+	}
+
+	// the security groups are attached in order
+	securityGroupIds := []string{state.Get("security_group_id").(string)}
+	if rawIds, ok := state.GetOk("security_group_ids"); ok {
+		for _, id := range rawIds.([]string) {
+			if !containsString(securityGroupIds, id) {
+				securityGroupIds = append(securityGroupIds, id)
+			}
+		}
+	}
+	return securityGroupIds, false
+}
@@ -0,0 +1,417 @@
+package bcc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/baidubce/bce-sdk-go/services/bcc"
+	"github.com/baidubce/bce-sdk-go/services/bcc/api"
+	"github.com/baidubce/bce-sdk-go/services/vpc"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// accountIdPattern is the format of baiducloud account ids
+var accountIdPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// stepDryRun runs the read-only checks against the account, and prints the
+// plan of the resources which would be created and deleted by the build,
+// without creating anything
+type stepDryRun struct{}
+
+func (s *stepDryRun) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	ui.Say("Running dry run checks, no resource will be created...")
+
+	type check struct {
+		name  string
+		check func(context.Context, multistep.StateBag) error
+	}
+	checks := []check{
+		{"instance specs", s.checkInstanceSpecs},
+		{"network", s.checkNetwork},
+		{"keypair", s.checkKeyPair},
+		{"placement", s.checkPlacement},
+		{"image names", s.checkImageNames},
+		{"share accounts", s.checkShareAccounts},
+	}
+	if config.ReuseInstanceId != "" {
+		// the reused instance keeps its own spec, network and placement
+		checks = []check{
+			{"reused instance", s.checkExistingInstance},
+			{"keypair", s.checkKeyPair},
+			{"image names", s.checkImageNames},
+			{"share accounts", s.checkShareAccounts},
+		}
+	}
+	if config.SourceInstanceId != "" {
+		// the image is captured from the source instance as it is
+		checks = []check{
+			{"source instance", s.checkExistingInstance},
+			{"image names", s.checkImageNames},
+			{"share accounts", s.checkShareAccounts},
+		}
+	}
+
+	var errs *packersdk.MultiError
+	for _, c := range checks {
+		ui.Say(fmt.Sprintf("Checking %s...", c.name))
+		if err := c.check(ctx, state); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("%s: %w", c.name, ClassifyError(err)))
+		}
+	}
+
+	ui.Say("Plan of the build:")
+	for _, line := range s.plan(config) {
+		ui.Message(line)
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return halt(state, errs, "Dry run checks failed")
+	}
+	ui.Say("Dry run checks passed")
+	return multistep.ActionContinue
+}
+
+func (s *stepDryRun) Cleanup(state multistep.StateBag) {}
+
+// checkInstanceSpecs checks that at least one of the candidate specs is
+// available in one of the candidate zones
+func (s *stepDryRun) checkInstanceSpecs(ctx context.Context, state multistep.StateBag) error {
+	client := state.Get("client").(*bcc.Client)
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	available := 0
+	for _, zone := range config.candidateZones() {
+		var listResult *api.ListFlavorSpecResult
+		err := Retry(ctx, func(ctx context.Context) error {
+			var e error
+			listResult, e = client.ListFlavorSpec(&api.ListFlavorSpecArgs{ZoneName: zone})
+			return e
+		})
+		if err != nil {
+			return err
+		}
+
+		specs := map[string]bool{}
+		for _, zoneResource := range listResult.ZoneResources {
+			for _, group := range zoneResource.BccResources.FlavorGroups {
+				for _, flavor := range group.Flavors {
+					specs[flavor.Spec] = true
+				}
+			}
+		}
+		for _, spec := range config.candidateInstanceSpecs() {
+			if specs[spec] {
+				available++
+				ui.Message(fmt.Sprintf("Instance spec %s is available in zone(%s)", spec, zone))
+			} else {
+				ui.Message(fmt.Sprintf("Instance spec %s isn't available in zone(%s)", spec, zone))
+			}
+		}
+	}
+
+	if available == 0 {
+		return errors.New("none of the instance specs is available in the zones")
+	}
+	return nil
+}
+
+// checkExistingInstance checks that the reused or source instance exists, and
+// can be rebuilt or captured
+func (s *stepDryRun) checkExistingInstance(ctx context.Context, state multistep.StateBag) error {
+	client := state.Get("client").(*bcc.Client)
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	instanceDetail, err := client.GetInstanceDetail(config.existingInstanceId())
+	if err != nil {
+		return fmt.Errorf("failed to get instance(%s): %w", config.existingInstanceId(), err)
+	}
+	instance := instanceDetail.Instance
+	if instance.Status != api.InstanceStatusStopped && instance.Status != api.InstanceStatusRunning {
+		return fmt.Errorf("the instance(%s) is %s, it must be stopped or running", instance.InstanceId, instance.Status)
+	}
+	ui.Message(fmt.Sprintf("Instance %s of spec %s in zone(%s) is %s",
+		instance.InstanceId, instance.Spec, instance.ZoneName, instance.Status))
+	return nil
+}
+
+// checkNetwork checks the existence of the vpc, subnet and security group,
+// and that the subnet and security group belong to the vpc
+func (s *stepDryRun) checkNetwork(ctx context.Context, state multistep.StateBag) error {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packersdk.Ui)
+	client := state.Get("client").(*bcc.Client)
+	if len(config.EnterpriseSecurityGroupIds) > 0 {
+		if err := checkEnterpriseSecurityGroups(ctx, client, config.EnterpriseSecurityGroupIds); err != nil {
+			return err
+		}
+	}
+	if config.UseDefaultNetwork || (config.VpcId == "" && config.VpcFilter.Empty()) {
+		return nil
+	}
+
+	vpcClient := state.Get("vpc_client").(*vpc.Client)
+	vpcId := config.VpcId
+	if !config.VpcFilter.Empty() {
+		var err error
+		if vpcId, err = findVpc(ctx, vpcClient, &config.VpcFilter); err != nil {
+			return err
+		}
+		ui.Message(fmt.Sprintf("Found vpc: %s", vpcId))
+	}
+	vpcDetail, err := vpcClient.GetVPCDetail(vpcId)
+	if err != nil {
+		return fmt.Errorf("failed to get vpc(%s): %w", vpcId, err)
+	}
+	if config.SubnetId == "" && config.SubnetFilter.Empty() && config.SubnetCidrBlock == cidrAuto {
+		cidr, err := pickFreeSubnetCidr(&vpcDetail.VPC, config.SubnetCidrPrefixLength, config.AvoidCidrs)
+		if err != nil {
+			return err
+		}
+		ui.Message(fmt.Sprintf("The temporary subnet would use cidr block %s", cidr))
+	}
+
+	subnetId := config.SubnetId
+	if !config.SubnetFilter.Empty() {
+		var zones []string
+		if config.Zone != "" {
+			zones = config.candidateZones()
+		}
+		var err error
+		if subnetId, err = findSubnet(ctx, vpcClient, vpcId, &config.SubnetFilter, zones); err != nil {
+			return err
+		}
+		ui.Message(fmt.Sprintf("Found subnet: %s", subnetId))
+	}
+	if subnetId != "" {
+		subnetDetail, err := vpcClient.GetSubnetDetail(subnetId)
+		if err != nil {
+			return fmt.Errorf("failed to get subnet(%s): %w", subnetId, err)
+		}
+		subnet := subnetDetail.Subnet
+		if subnet.VPCId != vpcId {
+			return fmt.Errorf("the subnet(%s) belongs to vpc(%s), not vpc(%s)", subnetId, subnet.VPCId, vpcId)
+		}
+		if zones := config.candidateZones(); config.Zone != "" && !containsString(zones, subnet.ZoneName) {
+			return fmt.Errorf("the subnet(%s) is in zone(%s), not in zone(%s)", subnetId, subnet.ZoneName, strings.Join(zones, ", "))
+		}
+		if subnet.AvailableIp < 1 {
+			return fmt.Errorf("the subnet(%s) has no free ip", subnetId)
+		}
+	}
+
+	securityGroupId := config.SecurityGroupId
+	if !config.SecurityGroupFilter.Empty() {
+		var err error
+		if securityGroupId, err = findSecurityGroup(ctx, client, vpcId, &config.SecurityGroupFilter); err != nil {
+			return err
+		}
+		ui.Message(fmt.Sprintf("Found security group: %s", securityGroupId))
+	}
+	securityGroupIds := config.SecurityGroupIds
+	if securityGroupId != "" {
+		securityGroupIds = append([]string{securityGroupId}, securityGroupIds...)
+	}
+	if len(securityGroupIds) > 0 {
+		if err := checkSecurityGroups(ctx, client, vpcId, securityGroupIds...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkKeyPair checks the existence of the keypair
+func (s *stepDryRun) checkKeyPair(ctx context.Context, state multistep.StateBag) error {
+	client := state.Get("client").(*bcc.Client)
+	config := state.Get("config").(*Config)
+
+	if config.KeypairId != "" {
+		if _, err := client.GetKeypairDetail(config.KeypairId); err != nil {
+			return fmt.Errorf("failed to get keypair(%s): %w", config.KeypairId, err)
+		}
+	} else if config.Comm.SSHKeyPairName != "" {
+		if _, err := findKeyPairId(ctx, client, config.Comm.SSHKeyPairName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkPlacement checks the existence of the dedicated host and deployment
+// set, and that they can be used in every zone the instance may be launched in
+func (s *stepDryRun) checkPlacement(ctx context.Context, state multistep.StateBag) error {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	var zones []string
+	switch {
+	case config.SubnetId != "":
+		// the instance can only be launched in the zone of the subnet
+		vpcClient := state.Get("vpc_client").(*vpc.Client)
+		subnetDetail, err := vpcClient.GetSubnetDetail(config.SubnetId)
+		if err != nil {
+			return fmt.Errorf("failed to get subnet(%s): %w", config.SubnetId, err)
+		}
+		zones = []string{subnetDetail.Subnet.ZoneName}
+	case config.SubnetFilter.Empty():
+		for _, zone := range config.candidateZones() {
+			if zone != "" {
+				zones = append(zones, zone)
+			}
+		}
+	}
+	if len(zones) == 0 {
+		ui.Message("The zone is only known after the subnet is found, the placement zone isn't checked")
+	}
+
+	return checkPlacement(state, config.DedicatedHostId, config.DeploymentSetId, zones)
+}
+
+// checkImageNames checks that the image name isn't used in the destination
+// regions. The source region is checked by stepPreValidate.
+func (s *stepDryRun) checkImageNames(ctx context.Context, state multistep.StateBag) error {
+	config := state.Get("config").(*Config)
+	if config.SkipImageValidation || config.SkipCreateImage {
+		return nil
+	}
+
+	for _, region := range config.DestinationRegions {
+		client, err := config.ClientWithRegion(region)
+		if err != nil {
+			return err
+		}
+		listResult, err := client.ListImage(&api.ListImageArgs{
+			ImageName: config.ImageName,
+			ImageType: string(api.ImageTypeCustom),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list images of region(%s): %w", region, err)
+		}
+		if len(listResult.Images) > 0 {
+			return fmt.Errorf("image name %s has exists in region(%s)", config.ImageName, region)
+		}
+	}
+	return nil
+}
+
+// checkShareAccounts checks the format of the account ids, and that the
+// account names aren't empty. There is no api to check the existence of an
+// account, so it's only verified while sharing.
+func (s *stepDryRun) checkShareAccounts(ctx context.Context, state multistep.StateBag) error {
+	config := state.Get("config").(*Config)
+
+	for _, account := range config.ImageShareAccounts {
+		if strings.TrimSpace(account) == "" {
+			return errors.New("empty account name in 'image_share_accounts'")
+		}
+	}
+
+	var invalid []string
+	for _, accountId := range config.ImageShareAccountIds {
+		if !accountIdPattern.MatchString(accountId) {
+			invalid = append(invalid, accountId)
+		}
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid account ids: %s", strings.Join(invalid, ", "))
+	}
+	return nil
+}
+
+// plan describes the resources which would be created by the build, and
+// whether they would be deleted afterwards
+func (s *stepDryRun) plan(config *Config) []string {
+	var plan []string
+	temporary := func(format string, a ...interface{}) {
+		plan = append(plan, "+/- "+fmt.Sprintf(format, a...)+" (deleted after build)")
+	}
+	created := func(format string, a ...interface{}) {
+		plan = append(plan, "+   "+fmt.Sprintf(format, a...))
+	}
+
+	comm := config.Comm
+	if comm.SSHTemporaryKeyPairName != "" && (comm.SSHPrivateKeyFile == "" || config.SSHPublicKeyFile != "") &&
+		!(comm.SSHAgentAuth && config.SSHPublicKeyFile == "") {
+		if config.SSHPublicKeyFile != "" || config.ImportTemporaryKeyPair {
+			temporary("keypair %s imported from a local public key", comm.SSHTemporaryKeyPairName)
+		} else {
+			temporary("keypair %s", comm.SSHTemporaryKeyPairName)
+		}
+	}
+	if !config.UseDefaultNetwork && config.existingInstanceId() == "" {
+		if config.VpcId == "" && config.VpcFilter.Empty() {
+			temporary("vpc %s with cidr %s", config.VpcName, config.CidrBlock)
+		}
+		if config.SubnetId == "" && config.SubnetFilter.Empty() {
+			temporary("subnet %s with cidr %s", config.SubnetName, config.SubnetCidrBlock)
+		}
+		if config.SecurityGroupId == "" && config.SecurityGroupFilter.Empty() && len(config.EnterpriseSecurityGroupIds) == 0 {
+			temporary("security group %s", config.SecurityGroupName)
+		}
+	}
+	if config.TemporaryNatGateway {
+		temporary("eip with %d Mbps bandwidth for nat gateway", config.TemporaryNatGatewayBandwidthInMbps)
+		temporary("nat gateway, snat rule and route rule to the internet")
+	}
+	if config.AutoCreateDeploymentSet {
+		temporary("deployment set")
+	}
+
+	if config.SourceInstanceId != "" {
+		instance := fmt.Sprintf("    instance %s captured as it is (never deleted)", config.SourceInstanceId)
+		if config.StopSourceInstance {
+			instance = fmt.Sprintf("~   instance %s stopped and captured (never deleted)", config.SourceInstanceId)
+		}
+		plan = append(plan, instance)
+	} else if config.ReuseInstanceId != "" {
+		plan = append(plan, fmt.Sprintf("~   instance %s rebuilt from image %s (stopped after build)",
+			config.ReuseInstanceId, config.SourceImageId))
+	} else {
+		instance := fmt.Sprintf("instance %s of spec %s in zone %s from image %s", config.InstanceName,
+			strings.Join(config.candidateInstanceSpecs(), "|"), strings.Join(config.candidateZones(), "|"), config.SourceImageId)
+		if config.SpotStrategy != SpotStrategyNone {
+			instance += fmt.Sprintf(", spot strategy %s", config.SpotStrategy)
+		}
+		temporary("%s", instance)
+	}
+	for _, ni := range config.NetworkInterfaces {
+		subnet := ni.SubnetId
+		if subnet == "" {
+			subnet = "of the instance"
+		}
+		temporary("network interface in subnet %s with %d secondary private ips", subnet, ni.SecondaryPrivateIpCount)
+	}
+	if bastion := config.TemporaryBastion; bastion != nil {
+		temporary("bastion instance of spec %s from image %s, with an eip of %d Mbps bandwidth",
+			bastion.InstanceSpec, bastion.SourceImageId, bastion.NetworkCapacityInMbps)
+	}
+	if config.AssociatePublicIpAddress && config.existingInstanceId() == "" {
+		temporary("eip %s with %d Mbps bandwidth", config.EipName, config.NetworkCapacityInMbps)
+	}
+
+	if config.SkipCreateImage {
+		plan = append(plan, "    no image is created, since 'skip_create_image' is true")
+		return plan
+	}
+	created("image %s in region %s", config.ImageName, config.BaiduCloudRegion)
+	for _, region := range config.DestinationRegions {
+		created("image %s copied to region %s", config.ImageName, region)
+	}
+	if len(config.ImageShareAccountIds) > 0 {
+		plan = append(plan, fmt.Sprintf("    images shared to account ids %s", strings.Join(config.ImageShareAccountIds, ", ")))
+	}
+	if len(config.ImageShareAccounts) > 0 {
+		plan = append(plan, fmt.Sprintf("    images shared to accounts %s (the account names are only checked while sharing)",
+			strings.Join(config.ImageShareAccounts, ", ")))
+	}
+	return plan
+}
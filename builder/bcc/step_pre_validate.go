@@ -3,13 +3,27 @@ package bcc
 import (
 	"context"
 	"fmt"
+	"strconv"
 
+	"github.com/baidubce/bce-sdk-go/bce"
+	"github.com/baidubce/bce-sdk-go/http"
 	"github.com/baidubce/bce-sdk-go/services/bcc"
 	"github.com/baidubce/bce-sdk-go/services/bcc/api"
+	"github.com/baidubce/bce-sdk-go/services/quotacenter"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 )
 
+// quotaCheck is a quota in the quota center, and the amount of it the build
+// needs in a region
+type quotaCheck struct {
+	description string
+	serviceType string
+	name        string
+	region      string
+	need        int
+}
+
 type stepPreValidate struct {
 	SourceImageId       string
 	CustomImageName     string
@@ -17,17 +31,45 @@ type stepPreValidate struct {
 }
 
 func (s *stepPreValidate) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
-	if s.SkipImageValidation {
-		return multistep.ActionContinue
+	config := state.Get("config").(*Config)
+
+	if !s.SkipImageValidation {
+		if action := s.validateImage(ctx, state); action != multistep.ActionContinue {
+			return action
+		}
+	}
+
+	if err := s.checkQuotas(ctx, state); err != nil {
+		return halt(state, err, "Quota check failed")
 	}
 
+	if config.CheckBalance {
+		if err := s.checkBalance(ctx, state); err != nil {
+			return halt(state, err, "Balance check failed")
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepPreValidate) Cleanup(multistep.StateBag) {}
+
+func (s *stepPreValidate) validateImage(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	client := state.Get("client").(*bcc.Client)
 	ui := state.Get("ui").(packersdk.Ui)
 
-	ui.Say("Trying to check source image id...")
-	_, err := client.GetImageDetail(s.SourceImageId)
-	if err != nil {
-		return halt(state, err, fmt.Sprintf("The source image(id:%s) doesn't exist", s.SourceImageId))
+	// the image is captured from the source instance if there is no
+	// source image
+	if s.SourceImageId != "" {
+		ui.Say("Trying to check source image id...")
+		_, err := client.GetImageDetail(s.SourceImageId)
+		if err != nil {
+			return halt(state, err, fmt.Sprintf("The source image(id:%s) doesn't exist", s.SourceImageId))
+		}
+	}
+
+	if config := state.Get("config").(*Config); config.SkipCreateImage {
+		return multistep.ActionContinue
 	}
 
 	ui.Say("Trying to check custom image name...")
@@ -47,4 +89,140 @@ func (s *stepPreValidate) Run(ctx context.Context, state multistep.StateBag) mul
 	return multistep.ActionContinue
 }
 
-func (s *stepPreValidate) Cleanup(multistep.StateBag) {}
+// checkQuotas checks that the quotas needed by the build aren't exhausted.
+// The quotas which fail to be queried or aren't found in the quota center
+// are skipped.
+func (s *stepPreValidate) checkQuotas(ctx context.Context, state multistep.StateBag) error {
+	ui := state.Get("ui").(packersdk.Ui)
+	quotaClient := state.Get("quota_client").(*quotacenter.Client)
+
+	checks := s.getQuotaChecks(state)
+	if len(checks) == 0 {
+		return nil
+	}
+
+	ui.Say("Trying to check quotas...")
+	var errs *packersdk.MultiError
+	for _, check := range checks {
+		var listResult *quotacenter.ListQuotaResult
+		err := Retry(ctx, func(ctx context.Context) error {
+			var e error
+			listResult, e = quotaClient.QuotaCenterQuery(&quotacenter.QuotaCenterQueryArgs{
+				Type:        "QUOTA",
+				ServiceType: check.serviceType,
+				Region:      check.region,
+				Name:        check.name,
+			})
+			return e
+		})
+		if ErrorKindOf(err) == ErrorKindAuth {
+			// the quota center may not be accessible by restricted accounts
+			ui.Message(fmt.Sprintf("Quota center isn't accessible, quota checks are skipped: %s", err))
+			break
+		}
+		if err != nil {
+			// the quota center may not support the region or the quota, so
+			// only an exhausted quota fails the build
+			ui.Message(fmt.Sprintf("Failed to query %s quota of region(%s), skipped: %s", check.description, check.region, err))
+			continue
+		}
+
+		checked := false
+		for _, quota := range listResult.Result {
+			if quota.Name != check.name {
+				continue
+			}
+			value, valueErr := strconv.Atoi(quota.Value)
+			used, usedErr := strconv.Atoi(quota.Used)
+			if valueErr != nil || usedErr != nil || value < 0 {
+				// unlimited or unknown quota
+				break
+			}
+			checked = true
+			if used+check.need > value {
+				errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("the %s quota of region(%s) is exhausted: %d of %d used, %d needed (%s error: %s)",
+					check.description, check.region, used, value, check.need, ErrorKindQuota, hintQuota))
+			} else {
+				ui.Message(fmt.Sprintf("The %s quota of region(%s): %d of %d used", check.description, check.region, used, value))
+			}
+		}
+		if !checked {
+			ui.Message(fmt.Sprintf("The %s quota of region(%s) isn't limited or found, skipped", check.description, check.region))
+		}
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// getQuotaChecks returns the quotas to check according to the resources the
+// build will create
+func (s *stepPreValidate) getQuotaChecks(state multistep.StateBag) []quotaCheck {
+	config := state.Get("config").(*Config)
+	region := config.BaiduCloudRegion
+
+	var checks []quotaCheck
+	if !config.SkipInstanceQuotaCheck && config.existingInstanceId() == "" {
+		instances := 1
+		if config.TemporaryBastion != nil {
+			instances++
+		}
+		checks = append(checks, quotaCheck{"instance", "BCC", "bccInstanceQuota", region, instances})
+	}
+	if !config.UseDefaultNetwork && config.existingInstanceId() == "" {
+		if !config.SkipVpcQuotaCheck && config.VpcId == "" && config.VpcFilter.Empty() {
+			checks = append(checks, quotaCheck{"vpc", "VPC", "vpcQuota", region, 1})
+		}
+		if !config.SkipSubnetQuotaCheck && config.SubnetId == "" && config.SubnetFilter.Empty() {
+			checks = append(checks, quotaCheck{"subnet", "VPC", "subnetQuota", region, 1})
+		}
+		if !config.SkipSecurityGroupQuotaCheck && config.SecurityGroupId == "" && config.SecurityGroupFilter.Empty() &&
+			len(config.EnterpriseSecurityGroupIds) == 0 {
+			checks = append(checks, quotaCheck{"security group", "BCC", "securityGroupQuota", region, 1})
+		}
+	}
+	eips := 0
+	// the existing instance keeps its own eip
+	newEip := config.AssociatePublicIpAddress && config.existingInstanceId() == ""
+	for _, needed := range []bool{newEip, config.TemporaryNatGateway, config.TemporaryBastion != nil} {
+		if needed {
+			eips++
+		}
+	}
+	if !config.SkipEipQuotaCheck && eips > 0 {
+		checks = append(checks, quotaCheck{"eip", "EIP", "eipInstanceQuota", region, eips})
+	}
+
+	if !config.SkipImageQuotaCheck && !config.SkipCreateImage {
+		for _, r := range append([]string{region}, config.DestinationRegions...) {
+			checks = append(checks, quotaCheck{"custom image", "BCC", "customImageQuota", r, 1})
+		}
+	}
+	return checks
+}
+
+// checkBalance checks that the cash balance of the account isn't negative
+func (s *stepPreValidate) checkBalance(ctx context.Context, state multistep.StateBag) error {
+	ui := state.Get("ui").(packersdk.Ui)
+	billingClient := state.Get("billing_client").(bce.Client)
+
+	ui.Say("Trying to check account balance...")
+	result := &struct {
+		CashBalance float64 `json:"cashBalance"`
+	}{}
+	err := Retry(ctx, func(ctx context.Context) error {
+		return SendRequest(billingClient, http.GET, "/v1/finance/cash/balance", nil, nil, result)
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to query account balance: %w", err)
+	}
+	// credit or invoiced accounts may have no cash balance without being in
+	// arrears
+	if result.CashBalance < 0 {
+		return fmt.Errorf("the cash balance of the account is %.2f (%s error: %s)", result.CashBalance, ErrorKindBilling, hintBilling)
+	}
+	ui.Message(fmt.Sprintf("The cash balance of the account: %.2f", result.CashBalance))
+	return nil
+}
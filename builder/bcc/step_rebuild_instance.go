@@ -0,0 +1,106 @@
+package bcc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/baidubce/bce-sdk-go/services/bcc"
+	"github.com/baidubce/bce-sdk-go/services/bcc/api"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// stepRebuildInstance rebuilds an existing instance from the source image
+// instead of launching a new one. The instance keeps its spec and network,
+// and is stopped rather than deleted after the build.
+type stepRebuildInstance struct {
+	InstanceId    string
+	SourceImageId string
+	rebuilt       bool
+}
+
+func (s *stepRebuildInstance) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*bcc.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	ui.Say(fmt.Sprintf("Trying to check instance(%s) to reuse...", s.InstanceId))
+	instanceDetail, err := client.GetInstanceDetail(s.InstanceId)
+	if err != nil {
+		return halt(state, err, fmt.Sprintf("Failed to get instance(%s), it may not exist", s.InstanceId))
+	}
+	switch instanceDetail.Instance.Status {
+	case api.InstanceStatusStopped:
+	case api.InstanceStatusRunning:
+		ui.Say(fmt.Sprintf("Stopping instance(%s) before rebuilding...", s.InstanceId))
+		if err := stopInstance(ctx, client, s.InstanceId); err != nil {
+			return halt(state, err, fmt.Sprintf("Failed to stop instance(%s)", s.InstanceId))
+		}
+	default:
+		return halt(state, fmt.Errorf("the instance(%s) is %s, it must be stopped or running",
+			s.InstanceId, instanceDetail.Instance.Status), "")
+	}
+
+	ui.Say(fmt.Sprintf("Rebuilding instance(%s) from image(%s)...", s.InstanceId, s.SourceImageId))
+	keypairId, password := instanceCredentials(state)
+	args := &rebuildInstanceArgs{
+		ImageId:   s.SourceImageId,
+		AdminPass: password,
+		KeypairId: keypairId,
+	}
+	err = Retry(ctx, func(ctx context.Context) error {
+		return rebuildInstance(client, s.InstanceId, args)
+	})
+	if err != nil {
+		return halt(state, err, fmt.Sprintf("Failed to rebuild instance(%s)", s.InstanceId))
+	}
+	s.rebuilt = true
+
+	ui.Say(fmt.Sprintf("Waiting for instance(%s) ready...", s.InstanceId))
+	if err := WaitForInstance(ctx, client, s.InstanceId, api.InstanceStatusRunning, 1800); err != nil {
+		return halt(state, err, fmt.Sprintf("Failed to wait for instance(%s) ready", s.InstanceId))
+	}
+	instanceDetail, err = client.GetInstanceDetail(s.InstanceId)
+	if err != nil {
+		return halt(state, err, fmt.Sprintf("Failed to get instance detail: %s", err))
+	}
+	instance := instanceDetail.Instance
+	ui.Message(fmt.Sprintf("Success to rebuild instance %s, public ip is %s, private ip is %s",
+		instance.InstanceId, instance.PublicIP, instance.InternalIP))
+	state.Put("instance_id", s.InstanceId)
+	state.Put("instance", &instance)
+	state.Put("instance_spec", instance.Spec)
+	state.Put("zone", instance.ZoneName)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepRebuildInstance) Cleanup(state multistep.StateBag) {
+	if !s.rebuilt {
+		return
+	}
+
+	client := state.Get("client").(*bcc.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	// the instance may have been stopped, e.g. by sysprep
+	instanceDetail, err := client.GetInstanceDetail(s.InstanceId)
+	if err == nil && instanceDetail.Instance.Status == api.InstanceStatusStopped {
+		return
+	}
+
+	ui.Say(fmt.Sprintf("Stopping reused instance(%s)...", s.InstanceId))
+	if err := stopInstance(context.TODO(), client, s.InstanceId); err != nil {
+		ui.Error(fmt.Sprintf("Failed to stop instance(%s), please stop it manually: %s", s.InstanceId, err))
+	}
+}
+
+// stopInstance stops the instance and waits until it's stopped
+func stopInstance(ctx context.Context, client *bcc.Client, instanceId string) error {
+	err := Retry(ctx, func(ctx context.Context) error {
+		return client.StopInstance(instanceId, false)
+	})
+	if err != nil {
+		return err
+	}
+	return WaitForInstance(ctx, client, instanceId, api.InstanceStatusStopped, 600)
+}
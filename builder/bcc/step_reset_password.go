@@ -0,0 +1,53 @@
+package bcc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/baidubce/bce-sdk-go/services/bcc"
+	"github.com/baidubce/bce-sdk-go/services/bcc/api"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// stepResetPassword resets the temporary admin password to another random
+// one after provision, so that the image doesn't keep a known password
+type stepResetPassword struct {
+	Enabled bool
+}
+
+func (s *stepResetPassword) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	if !s.Enabled {
+		return multistep.ActionContinue
+	}
+
+	client := state.Get("client").(*bcc.Client)
+	instanceId := state.Get("instance_id").(string)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	password, err := generatePassword()
+	if err != nil {
+		return halt(state, err, "Failed to generate password")
+	}
+	packersdk.LogSecretFilter.Set(password)
+
+	ui.Say(fmt.Sprintf("Resetting the temporary password of instance(%s)...", instanceId))
+	err = Retry(ctx, func(ctx context.Context) error {
+		// the args are encrypted in place by the sdk, so they can't be reused
+		return client.ChangeInstancePass(instanceId, &api.ChangeInstancePassArgs{
+			AdminPass: password,
+		})
+	})
+	if err != nil {
+		return halt(state, err, "Failed to reset password")
+	}
+
+	// Wait instance running
+	if err := WaitForInstance(ctx, client, instanceId, api.InstanceStatusRunning, 600); err != nil {
+		return halt(state, err, "Failed to wait instance running after reset password")
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepResetPassword) Cleanup(state multistep.StateBag) {}
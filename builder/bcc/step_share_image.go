@@ -15,7 +15,11 @@ type stepShareImage struct {
 }
 
 func (s *stepShareImage) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
-	baiduCloudImages := state.Get("baiducloud_images").(map[string]string)
+	rawImages, ok := state.GetOk("baiducloud_images")
+	if !ok {
+		return multistep.ActionContinue
+	}
+	baiduCloudImages := rawImages.(map[string]string)
 	config := state.Get("config").(*Config)
 	ui := state.Get("ui").(packersdk.Ui)
 
@@ -53,13 +57,14 @@ func (s *stepShareImage) Cleanup(state multistep.StateBag) {
 	}
 
 	shareAccountArgsList := s.getShareAccountArgsList()
-	if len(shareAccountArgsList) == 0 {
+	rawImages, ok := state.GetOk("baiducloud_images")
+	if len(shareAccountArgsList) == 0 || !ok {
 		return
 	}
 
 	ui := state.Get("ui").(packersdk.Ui)
 	config := state.Get("config").(*Config)
-	baiduCloudImages := state.Get("baiducloud_images").(map[string]string)
+	baiduCloudImages := rawImages.(map[string]string)
 	ctx := context.TODO()
 
 	ui.Error("Cancel image share because cancellations or error...")
@@ -0,0 +1,89 @@
+package bcc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/baidubce/bce-sdk-go/services/bcc"
+	"github.com/baidubce/bce-sdk-go/services/bcc/api"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// stepSourceInstance uses an existing instance to capture the image from,
+// instead of launching a new one. The instance is never deleted.
+type stepSourceInstance struct {
+	InstanceId string
+	// Start a stopped instance, so that the provisioners can connect to it.
+	// It's stopped again after the build.
+	Start   bool
+	started bool
+}
+
+func (s *stepSourceInstance) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	client := state.Get("client").(*bcc.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	ui.Say(fmt.Sprintf("Trying to check source instance(%s)...", s.InstanceId))
+	instanceDetail, err := client.GetInstanceDetail(s.InstanceId)
+	if err != nil {
+		return halt(state, err, fmt.Sprintf("Failed to get instance(%s), it may not exist", s.InstanceId))
+	}
+	switch instanceDetail.Instance.Status {
+	case api.InstanceStatusRunning:
+	case api.InstanceStatusStopped:
+		if !s.Start {
+			break
+		}
+		ui.Say(fmt.Sprintf("Starting instance(%s)...", s.InstanceId))
+		err := Retry(ctx, func(ctx context.Context) error {
+			return client.StartInstance(s.InstanceId)
+		})
+		if err != nil {
+			return halt(state, err, fmt.Sprintf("Failed to start instance(%s)", s.InstanceId))
+		}
+		s.started = true
+		if err := WaitForInstance(ctx, client, s.InstanceId, api.InstanceStatusRunning, 600); err != nil {
+			return halt(state, err, fmt.Sprintf("Failed to wait for instance(%s) running", s.InstanceId))
+		}
+		instanceDetail, err = client.GetInstanceDetail(s.InstanceId)
+		if err != nil {
+			return halt(state, err, fmt.Sprintf("Failed to get instance detail: %s", err))
+		}
+	default:
+		return halt(state, fmt.Errorf("the instance(%s) is %s, it must be stopped or running",
+			s.InstanceId, instanceDetail.Instance.Status), "")
+	}
+
+	instance := instanceDetail.Instance
+	ui.Message(fmt.Sprintf("Using source instance %s, public ip is %s, private ip is %s",
+		instance.InstanceId, instance.PublicIP, instance.InternalIP))
+	state.Put("instance_id", s.InstanceId)
+	state.Put("instance", &instance)
+	state.Put("instance_spec", instance.Spec)
+	state.Put("zone", instance.ZoneName)
+
+	return multistep.ActionContinue
+}
+
+// Cleanup never deletes the source instance, which belongs to the user, but
+// stops it again if it was started by the build
+func (s *stepSourceInstance) Cleanup(state multistep.StateBag) {
+	if !s.started {
+		return
+	}
+
+	client := state.Get("client").(*bcc.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	// the instance may have been stopped by 'stop_source_instance'
+	instanceDetail, err := client.GetInstanceDetail(s.InstanceId)
+	if err == nil && instanceDetail.Instance.Status == api.InstanceStatusStopped {
+		return
+	}
+
+	ui.Say(fmt.Sprintf("Stopping source instance(%s) again...", s.InstanceId))
+	if err := stopInstance(context.TODO(), client, s.InstanceId); err != nil {
+		ui.Error(fmt.Sprintf("Failed to stop instance(%s), please stop it manually: %s", s.InstanceId, err))
+	}
+}
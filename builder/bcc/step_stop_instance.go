@@ -10,6 +10,8 @@ import (
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 )
 
+// stepStopInstance stops the instance before creating the image, so that the
+// file systems are consistent
 type stepStopInstance struct {
 	ForceStop        bool
 	DisableStop      bool
@@ -17,13 +19,27 @@ type stepStopInstance struct {
 }
 
 func (s *stepStopInstance) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	if s.DisableStop {
+		return multistep.ActionContinue
+	}
+
 	client := state.Get("client").(*bcc.Client)
 	instanceId := state.Get("instance_id").(string)
 	ui := state.Get("ui").(packersdk.Ui)
 
-	pause(state)
+	instanceDetail, err := client.GetInstanceDetail(instanceId)
+	if err != nil {
+		return halt(state, err, fmt.Sprintf("Failed to get instance(%s)", instanceId))
+	}
+	if instanceDetail.Instance.Status == api.InstanceStatusStopped {
+		ui.Say(fmt.Sprintf("Instance(%s) is already stopped", instanceId))
+		return multistep.ActionContinue
+	}
 
-	err := client.StopInstanceWithNoCharge(instanceId, s.ForceStop, s.StopWithNoCharge)
+	ui.Say(fmt.Sprintf("Stopping instance(%s)...", instanceId))
+	err = Retry(ctx, func(ctx context.Context) error {
+		return client.StopInstanceWithNoCharge(instanceId, s.ForceStop, s.StopWithNoCharge)
+	})
 	if err != nil {
 		return halt(state, err, "Failed to stop instance")
 	}
@@ -0,0 +1,166 @@
+package bcc
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/baidubce/bce-sdk-go/services/bcc"
+	"github.com/baidubce/bce-sdk-go/services/bcc/api"
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
+)
+
+// stepTemporaryBastion launches a bastion instance with an eip in the subnet
+// of the build instance, and sets it as the ssh bastion of the communicator
+type stepTemporaryBastion struct {
+	Config       *TemporaryBastionConfig
+	Comm         *communicator.Config
+	InstanceName string
+	instanceId   string
+	keyFile      string
+}
+
+func (s *stepTemporaryBastion) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	if s.Config == nil {
+		return multistep.ActionContinue
+	}
+
+	client := state.Get("client").(*bcc.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	ui.Say("Creating temporary bastion instance...")
+	args := s.getCreateInstanceBySpecArgs(state)
+	var createResult *api.CreateInstanceBySpecResult
+	err := Retry(ctx, func(ctx context.Context) error {
+		var e error
+		createResult, e = createInstanceBySpec(client, args)
+		return e
+	})
+	if err != nil {
+		return halt(state, err, "Failed to create bastion instance")
+	}
+	if len(createResult.InstanceIds) == 0 {
+		return halt(state, fmt.Errorf("No instance id return"), "Failed to create bastion instance")
+	}
+	s.instanceId = createResult.InstanceIds[0]
+
+	ui.Say(fmt.Sprintf("Waiting for bastion instance %s ready...", s.instanceId))
+	if err := WaitForInstance(ctx, client, s.instanceId, api.InstanceStatusRunning, 1800); err != nil {
+		return halt(state, err, fmt.Sprintf("Failed to wait for bastion instance(%s) ready", s.instanceId))
+	}
+	instanceDetail, err := client.GetInstanceDetail(s.instanceId)
+	if err != nil {
+		return halt(state, err, fmt.Sprintf("Failed to get bastion instance detail: %s", err))
+	}
+	publicIp := instanceDetail.Instance.PublicIP
+	ui.Message(fmt.Sprintf("Success to create bastion instance %s, public ip is %s", s.instanceId, publicIp))
+
+	s.Comm.SSHBastionHost = publicIp
+	s.Comm.SSHBastionPort = 22
+	s.Comm.SSHBastionUsername = s.Config.SSHUsername
+	switch {
+	case s.Comm.SSHAgentAuth:
+		s.Comm.SSHBastionAgentAuth = true
+	case s.Comm.SSHPrivateKeyFile != "":
+		s.Comm.SSHBastionPrivateKeyFile = s.Comm.SSHPrivateKeyFile
+	case len(s.Comm.SSHPrivateKey) > 0:
+		// the bastion private key can only be read from file
+		if err := s.writeKeyFile(); err != nil {
+			return halt(state, err, "Failed to save the private key for bastion")
+		}
+		s.Comm.SSHBastionPrivateKeyFile = s.keyFile
+	default:
+		s.Comm.SSHBastionPassword = s.Comm.SSHPassword
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepTemporaryBastion) Cleanup(state multistep.StateBag) {
+	s.release(state)
+}
+
+// release deletes the bastion instance, it's safe to be called more than once
+func (s *stepTemporaryBastion) release(state multistep.StateBag) {
+	if s.keyFile != "" {
+		os.Remove(s.keyFile)
+		s.keyFile = ""
+	}
+	if s.instanceId == "" {
+		return
+	}
+
+	cleanUpMessage(state, "bastion instance")
+
+	client := state.Get("client").(*bcc.Client)
+	ui := state.Get("ui").(packersdk.Ui)
+	err := Retry(context.TODO(), func(ctx context.Context) error {
+		return client.DeleteInstanceWithRelateResource(s.instanceId, &api.DeleteInstanceWithRelateResourceArgs{
+			RelatedReleaseFlag: true,
+		})
+	})
+	if err != nil {
+		ui.Error(fmt.Sprintf("Failed to delete bastion instance(%s), please clean it manually: %s", s.instanceId, err))
+	}
+	s.instanceId = ""
+}
+
+func (s *stepTemporaryBastion) writeKeyFile() error {
+	f, err := ioutil.TempFile("", "packer-bastion-key")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	s.keyFile = f.Name()
+	if err := f.Chmod(0600); err != nil {
+		return err
+	}
+	_, err = f.Write(s.Comm.SSHPrivateKey)
+	return err
+}
+
+func (s *stepTemporaryBastion) getCreateInstanceBySpecArgs(state multistep.StateBag) *createInstanceBySpecArgs {
+	args := &api.CreateInstanceBySpecArgs{
+		ImageId: s.Config.SourceImageId,
+		Billing: api.Billing{
+			PaymentTiming: api.PaymentTimingPostPaid,
+		},
+		Spec:                  s.Config.InstanceSpec,
+		ZoneName:              state.Get("zone").(string),
+		SubnetId:              state.Get("subnet_id").(string),
+		PurchaseCount:         1,
+		Name:                  s.InstanceName + "-bastion",
+		EipName:               fmt.Sprintf("packer_%s", uuid.TimeOrderedUUID()[:8]),
+		NetWorkCapacityInMbps: s.Config.NetworkCapacityInMbps,
+		InternetChargeType:    "TRAFFIC_POSTPAID_BY_HOUR",
+		ClientToken:           uuid.TimeOrderedUUID(),
+	}
+	if keypairId, ok := state.GetOk("key_pair_id"); ok {
+		args.KeypairId = keypairId.(string)
+	} else if keypairId, ok := state.GetOk("temporary_key_pair_id"); ok {
+		args.KeypairId = keypairId.(string)
+	} else {
+		args.AdminPass = s.Comm.SSHPassword
+	}
+
+	extArgs := &createInstanceBySpecArgs{CreateInstanceBySpecArgs: args}
+	setSecurityGroups(state, extArgs)
+	return extArgs
+}
+
+// stepReleaseBastion deletes the temporary bastion as soon as it's no longer
+// needed
+type stepReleaseBastion struct {
+	Bastion *stepTemporaryBastion
+}
+
+func (s *stepReleaseBastion) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	s.Bastion.release(state)
+	return multistep.ActionContinue
+}
+
+func (s *stepReleaseBastion) Cleanup(state multistep.StateBag) {}
@@ -0,0 +1,139 @@
+package bcc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// userDataPollInterval is the interval of checking whether the user data is done
+const userDataPollInterval = 10 * time.Second
+
+// userDataLogLines is the number of log lines shown if the user data fails
+const userDataLogLines = 50
+
+const (
+	userDataStatusDone  = "done"
+	userDataStatusError = "error"
+)
+
+// cloudInitStatusScript prints the status of cloud-init. The boot-finished
+// file is written once all the modules have run, and the errors are recorded
+// in result.json
+const cloudInitStatusScript = `if [ ! -f /var/lib/cloud/instance/boot-finished ]; then
+  echo running
+elif [ ! -f /var/lib/cloud/data/result.json ] || grep -q '"errors": \[\]' /var/lib/cloud/data/result.json; then
+  echo done
+else
+  echo error
+fi`
+
+const cloudInitLogScript = `cat /var/lib/cloud/data/result.json 2>/dev/null
+(sudo -n tail -n %[1]d /var/log/cloud-init-output.log || tail -n %[1]d /var/log/cloud-init-output.log) 2>/dev/null
+true`
+
+// cloudbaseInitStatusScript prints the status of cloudbase-init, which marks
+// the user data plugin with 1 once it's executed
+const cloudbaseInitStatusScript = `$plugins = Get-ChildItem -Path "HKLM:\SOFTWARE\Cloudbase Solutions\Cloudbase-Init" -Recurse -ErrorAction SilentlyContinue |
+  Get-ItemProperty -Name UserDataPlugin -ErrorAction SilentlyContinue
+if ($plugins | Where-Object { $_.UserDataPlugin -eq 1 }) { "done" } else { "running" }`
+
+const cloudbaseInitLogScript = `Get-Content -Path "$env:ProgramFiles\Cloudbase Solutions\Cloudbase-Init\log\cloudbase-init.log" -Tail %d -ErrorAction SilentlyContinue`
+
+// stepWaitForUserData waits for cloud-init, or cloudbase-init on windows, to
+// finish running the user data before provisioning
+type stepWaitForUserData struct {
+	Enabled bool
+	Timeout time.Duration
+	Windows bool
+}
+
+func (s *stepWaitForUserData) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	if !s.Enabled {
+		return multistep.ActionContinue
+	}
+
+	comm := state.Get("communicator").(packersdk.Communicator)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	ui.Say(fmt.Sprintf("Waiting for user data to complete, timeout: %s...", s.Timeout))
+	waitCtx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+
+	for {
+		status, err := s.runCommand(waitCtx, comm, s.statusCommand())
+		if err != nil && waitCtx.Err() == nil {
+			return halt(state, err, "Failed to check the status of user data")
+		}
+
+		switch strings.TrimSpace(status) {
+		case userDataStatusDone:
+			ui.Message("User data is completed")
+			return multistep.ActionContinue
+		case userDataStatusError:
+			s.showLog(ctx, comm, ui)
+			return halt(state, errors.New("user data is completed with errors"), "")
+		}
+
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return halt(state, ctx.Err(), "Failed to wait for user data")
+			}
+			s.showLog(ctx, comm, ui)
+			return halt(state, fmt.Errorf("user data isn't completed in %s", s.Timeout), "")
+		case <-time.After(userDataPollInterval):
+		}
+	}
+}
+
+func (s *stepWaitForUserData) Cleanup(state multistep.StateBag) {}
+
+func (s *stepWaitForUserData) statusCommand() string {
+	if s.Windows {
+		return "powershell -NoProfile -ExecutionPolicy Bypass -EncodedCommand " + encodePowershellCommand(cloudbaseInitStatusScript)
+	}
+	return cloudInitStatusScript
+}
+
+func (s *stepWaitForUserData) logCommand() string {
+	if s.Windows {
+		return "powershell -NoProfile -ExecutionPolicy Bypass -EncodedCommand " +
+			encodePowershellCommand(fmt.Sprintf(cloudbaseInitLogScript, userDataLogLines))
+	}
+	return fmt.Sprintf(cloudInitLogScript, userDataLogLines)
+}
+
+// showLog shows the tail of the user data log, along with the result of
+// cloud-init on linux
+func (s *stepWaitForUserData) showLog(ctx context.Context, comm packersdk.Communicator, ui packersdk.Ui) {
+	output, err := s.runCommand(ctx, comm, s.logCommand())
+	if err != nil {
+		ui.Error(fmt.Sprintf("Failed to read the log of user data: %s", err))
+		return
+	}
+	ui.Error(fmt.Sprintf("The last %d lines of the user data log:\n%s", userDataLogLines, output))
+}
+
+// runCommand runs the command over the communicator and returns its stdout
+func (s *stepWaitForUserData) runCommand(ctx context.Context, comm packersdk.Communicator, command string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := &packersdk.RemoteCmd{
+		Command: command,
+		Stdout:  &stdout,
+		Stderr:  &stderr,
+	}
+	if err := comm.Start(ctx, cmd); err != nil {
+		return "", err
+	}
+	if status := cmd.Wait(); status != 0 {
+		return "", fmt.Errorf("command exited with status %d: %s", status, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
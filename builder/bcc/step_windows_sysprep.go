@@ -0,0 +1,58 @@
+package bcc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/baidubce/bce-sdk-go/services/bcc"
+	"github.com/baidubce/bce-sdk-go/services/bcc/api"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// winrmExitCodeEOF is the exit code reported by the winrm client if the
+// connection is closed by the remote host while running a command
+const winrmExitCodeEOF = 16001
+
+// stepWindowsSysprep generalizes the windows instance with sysprep, and waits
+// for the instance to stop before creating the image
+type stepWindowsSysprep struct {
+	Enabled bool
+}
+
+func (s *stepWindowsSysprep) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	if !s.Enabled {
+		return multistep.ActionContinue
+	}
+
+	client := state.Get("client").(*bcc.Client)
+	comm := state.Get("communicator").(packersdk.Communicator)
+	instanceId := state.Get("instance_id").(string)
+	ui := state.Get("ui").(packersdk.Ui)
+
+	ui.Say("Running sysprep to generalize the windows instance...")
+	cmd := &packersdk.RemoteCmd{
+		Command: "powershell -NoProfile -ExecutionPolicy Bypass -EncodedCommand " + encodePowershellCommand(windowsSysprepScript),
+	}
+	if err := cmd.RunWithUi(ctx, comm, ui); err != nil {
+		return halt(state, err, "Failed to run sysprep")
+	}
+	switch cmd.ExitStatus() {
+	case 0:
+	case winrmExitCodeEOF:
+		// the session may be closed once the shutdown is scheduled
+		ui.Message("The connection is closed by the shutting down instance")
+	default:
+		return halt(state, fmt.Errorf("sysprep exited with non-zero exit status: %d", cmd.ExitStatus()), "")
+	}
+
+	ui.Say(fmt.Sprintf("Waiting instance(%s) stop after sysprep...", instanceId))
+	err := WaitForInstance(ctx, client, instanceId, api.InstanceStatusStopped, 1800)
+	if err != nil {
+		return halt(state, err, "Failed to wait instance stopped after sysprep")
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepWindowsSysprep) Cleanup(state multistep.StateBag) {}
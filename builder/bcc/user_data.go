@@ -0,0 +1,88 @@
+package bcc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+)
+
+// maxUserDataSize is the max size of the base64 encoded user data accepted
+// by BCC
+const maxUserDataSize = 16 * 1024
+
+// userDataContentTypes maps the first line of a user data part to its
+// content type, see https://cloudinit.readthedocs.io/en/latest/explanation/format.html
+var userDataContentTypes = []struct {
+	prefix      string
+	contentType string
+}{
+	{"#cloud-config", "text/cloud-config"},
+	{"#cloud-boothook", "text/cloud-boothook"},
+	{"#include", "text/x-include-url"},
+	{"#part-handler", "text/part-handler"},
+	{"#upstart-job", "text/upstart-job"},
+	{"#!", "text/x-shellscript"},
+	{"#ps1", "text/x-shellscript"},
+}
+
+// userDataContentType detects the content type of a user data part
+func userDataContentType(content string) string {
+	for _, t := range userDataContentTypes {
+		if strings.HasPrefix(content, t.prefix) {
+			return t.contentType
+		}
+	}
+	return "text/plain"
+}
+
+// assembleUserData reads the files and assembles them into a MIME multi-part
+// document. Each file is passed to render before being added.
+func assembleUserData(files []string, render func(string) (string, error)) (string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=\"%s\"\r\nMIME-Version: 1.0\r\n\r\n", w.Boundary())
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		content := string(data)
+		if render != nil {
+			if content, err = render(content); err != nil {
+				return "", fmt.Errorf("failed to render %s: %s", file, err)
+			}
+		}
+
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", fmt.Sprintf("%s; charset=\"utf-8\"", userDataContentType(content)))
+		header.Set("MIME-Version", "1.0")
+		header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filepath.Base(file)))
+		part, err := w.CreatePart(header)
+		if err != nil {
+			return "", err
+		}
+		if _, err := part.Write([]byte(content)); err != nil {
+			return "", err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// checkUserDataSize checks the size of user data after base64 encoding
+func checkUserDataSize(userData string) error {
+	if size := base64.StdEncoding.EncodedLen(len(userData)); size > maxUserDataSize {
+		return fmt.Errorf("the size of base64 encoded user data is %d bytes, which exceeds the limit %d bytes",
+			size, maxUserDataSize)
+	}
+	return nil
+}
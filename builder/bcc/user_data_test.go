@@ -0,0 +1,48 @@
+package bcc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAssembleUserData(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	cloudConfig := filepath.Join(dir, "cloud-config.yaml")
+	script := filepath.Join(dir, "script.sh")
+	ioutil.WriteFile(cloudConfig, []byte("#cloud-config\nhostname: {{ .BuildName }}\n"), 0600)
+	ioutil.WriteFile(script, []byte("#!/bin/sh\necho hello\n"), 0600)
+
+	userData, err := assembleUserData([]string{cloudConfig, script}, func(content string) (string, error) {
+		return strings.Replace(content, "{{ .BuildName }}", "bcc", -1), nil
+	})
+	if err != nil {
+		t.Fatalf("Shouldn't raise error: %s", err)
+	}
+	for _, s := range []string{"multipart/mixed", "text/cloud-config", "text/x-shellscript", "hostname: bcc"} {
+		if !strings.Contains(userData, s) {
+			t.Fatalf("User data should contain %s: %s", s, userData)
+		}
+	}
+
+	if _, err := assembleUserData([]string{filepath.Join(dir, "missing.sh")}, nil); err == nil {
+		t.Fatalf("Should raise an error")
+	}
+}
+
+func TestCheckUserDataSize(t *testing.T) {
+	// 3 bytes are encoded into 4 bytes of base64
+	if err := checkUserDataSize(strings.Repeat("#", maxUserDataSize/4*3)); err != nil {
+		t.Fatalf("Shouldn't raise error: %s", err)
+	}
+	if err := checkUserDataSize(strings.Repeat("#", maxUserDataSize/4*3+1)); err == nil {
+		t.Fatalf("Should raise an error")
+	}
+}
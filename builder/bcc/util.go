@@ -2,11 +2,11 @@ package bcc
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
-	"os"
+	"math/big"
 	"time"
 
-	"github.com/baidubce/bce-sdk-go/bce"
 	"github.com/baidubce/bce-sdk-go/services/bcc"
 	"github.com/baidubce/bce-sdk-go/services/bcc/api"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
@@ -17,8 +17,9 @@ import (
 func halt(state multistep.StateBag, err error, prefix string) multistep.StepAction {
 	ui := state.Get("ui").(packersdk.Ui)
 
+	err = ClassifyError(err)
 	if prefix != "" {
-		err = fmt.Errorf("%s: %s", prefix, err)
+		err = fmt.Errorf("%s: %w", prefix, err)
 	}
 
 	state.Put("error", err)
@@ -45,16 +46,7 @@ func Retry(ctx context.Context, fn func(context.Context) error) error {
 	return retry.Config{
 		Tries: 60,
 		ShouldRetry: func(err error) bool {
-			e, ok := err.(*bce.BceServiceError)
-			if !ok {
-				return false
-			}
-			if e.Code == "Instance.DeleteServerFailException" || e.Code == "SecurityGroup.InstancesAssociatedSecurityGroupCanNotBeDeleted" ||
-				e.Code == "SECURITYGROUP_INUSE" || e.Code == "NotAllowDeleteVpc" || e.Code == "NotAllowOperateSubnet" ||
-				e.Code == "ResourceNeedRelease" || e.Code == "ServiceInternalError" || e.Code == "RateLimit" {
-				return true
-			}
-			return false
+			return ErrorKindOf(err) == ErrorKindTransient
 		},
 		RetryDelay: (&retry.Backoff{
 			InitialBackoff: 1 * time.Second,
@@ -64,6 +56,29 @@ func Retry(ctx context.Context, fn func(context.Context) error) error {
 	}.Run(ctx, fn)
 }
 
+// inUseRetryTimeout is how long the deletion of a resource is retried while
+// its dependencies are being released
+const inUseRetryTimeout = 3 * time.Minute
+
+// RetryInUse is like Retry, but also retries the errors of dependent
+// resources still in use for a bounded time. It's used while cleaning up,
+// since the dependencies deleted by the previous steps may take a while to
+// be released.
+func RetryInUse(ctx context.Context, fn func(context.Context) error) error {
+	return retry.Config{
+		StartTimeout: inUseRetryTimeout,
+		ShouldRetry: func(err error) bool {
+			kind := ErrorKindOf(err)
+			return kind == ErrorKindTransient || kind == ErrorKindInUse
+		},
+		RetryDelay: (&retry.Backoff{
+			InitialBackoff: 1 * time.Second,
+			MaxBackoff:     10 * time.Second,
+			Multiplier:     2,
+		}).Linear,
+	}.Run(ctx, fn)
+}
+
 // DefaultWaitForInterval is the default wait interval of query instance detail
 const DefaultWaitForInterval = 5
 
@@ -114,9 +129,50 @@ func WaitForImage(ctx context.Context, client *bcc.Client, imageId string, targe
 	return nil
 }
 
-func pause(state multistep.StateBag) {
-	ui := state.Get("ui").(packersdk.Ui)
-	anykey := make([]byte, 1)
-	ui.Say("Please enter any key to continue...")
-	os.Stdin.Read(anykey)
+func containsString(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	passwordLength   = 16
+	passwordLetters  = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	passwordDigits   = "0123456789"
+	passwordSymbols  = "!@#$%^*()"
+	passwordAllChars = passwordLetters + passwordDigits + passwordSymbols
+)
+
+// generatePassword generates a random password which meets the complexity
+// requirements of BCC: it contains lowercase and uppercase letters, digits
+// and symbols in `!@#$%^*()`
+func generatePassword() (string, error) {
+	charsets := []string{passwordLetters[:26], passwordLetters[26:], passwordDigits, passwordSymbols}
+	for len(charsets) < passwordLength {
+		charsets = append(charsets, passwordAllChars)
+	}
+
+	password := make([]byte, passwordLength)
+	for i, charset := range charsets {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+		if err != nil {
+			return "", err
+		}
+		password[i] = charset[n.Int64()]
+	}
+
+	// shuffle the password, so that the required characters aren't always
+	// at the beginning
+	for i := len(password) - 1; i > 0; i-- {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		j := n.Int64()
+		password[i], password[j] = password[j], password[i]
+	}
+	return string(password), nil
 }
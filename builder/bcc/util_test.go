@@ -0,0 +1,23 @@
+package bcc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGeneratePassword(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		password, err := generatePassword()
+		if err != nil {
+			t.Fatalf("Shouldn't raise error: %s", err)
+		}
+		if len(password) != passwordLength {
+			t.Fatalf("Password %s should be %d characters long", password, passwordLength)
+		}
+		for _, charset := range []string{passwordLetters[:26], passwordLetters[26:], passwordDigits, passwordSymbols} {
+			if !strings.ContainsAny(password, charset) {
+				t.Fatalf("Password %s should contain one of %s", password, charset)
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package bcc
+
+import (
+	"encoding/base64"
+	"unicode/utf16"
+)
+
+// winrmCertificateName is the friendly name of the temporary self-signed
+// certificate used by the WinRM https listener
+const winrmCertificateName = "packer-winrm"
+
+// winrmBootstrapUserData is used as the user data of windows instances if
+// neither `user_data` nor `user_data_file` is set. It's executed by
+// cloudbase-init, enables WinRM over http and https, and opens both ports in
+// the windows firewall. The https listener uses a temporary self-signed
+// certificate, which is removed by the sysprep step.
+const winrmBootstrapUserData = `#ps1_sysnative
+$ErrorActionPreference = "Stop"
+
+Enable-PSRemoting -SkipNetworkProfileCheck -Force
+Set-Item -Path WSMan:\localhost\Service\Auth\Basic -Value $true
+Set-Item -Path WSMan:\localhost\Service\AllowUnencrypted -Value $true
+Set-Item -Path WSMan:\localhost\MaxTimeoutms -Value 1800000
+
+$cert = New-SelfSignedCertificate -DnsName $env:COMPUTERNAME -CertStoreLocation Cert:\LocalMachine\My
+$cert.FriendlyName = "` + winrmCertificateName + `"
+Get-ChildItem -Path WSMan:\localhost\Listener | Where-Object { $_.Keys -contains "Transport=HTTPS" } | Remove-Item -Recurse -Force
+New-Item -Path WSMan:\localhost\Listener -Transport HTTPS -Address * -CertificateThumbPrint $cert.Thumbprint -Force
+
+New-NetFirewallRule -Name packer-winrm-http -DisplayName "packer winrm http" -Direction Inbound -Protocol TCP -LocalPort 5985 -Action Allow
+New-NetFirewallRule -Name packer-winrm-https -DisplayName "packer winrm https" -Direction Inbound -Protocol TCP -LocalPort 5986 -Action Allow
+
+Restart-Service -Name WinRM
+`
+
+// windowsSysprepScript generalizes the instance with sysprep and shuts it
+// down once sysprep finishes. Sysprep is run with /quit instead of /shutdown
+// so that the script is able to remove what the bootstrap script left behind
+// afterwards. Disabling basic auth may break the WinRM session, which is
+// fine since the shutdown has already been scheduled by then.
+const windowsSysprepScript = `$ErrorActionPreference = "Stop"
+
+& $env:SystemRoot\System32\Sysprep\Sysprep.exe /generalize /oobe /quiet /quit
+while ($true) {
+  $imageState = (Get-ItemProperty -Path HKLM:\SOFTWARE\Microsoft\Windows\CurrentVersion\Setup\State).ImageState
+  if ($imageState -eq "IMAGE_STATE_GENERALIZE_RESEAL_TO_OOBE") { break }
+  Write-Output "Waiting for sysprep, current image state: $imageState"
+  Start-Sleep -Seconds 10
+}
+shutdown.exe /s /t 30 /f /d p:4:1 /c "packer sysprep"
+
+Get-ChildItem -Path Cert:\LocalMachine\My | Where-Object { $_.FriendlyName -eq "` + winrmCertificateName + `" } | Remove-Item -Force
+Remove-NetFirewallRule -Name packer-winrm-http, packer-winrm-https -ErrorAction SilentlyContinue
+Set-Item -Path WSMan:\localhost\Service\AllowUnencrypted -Value $false
+Set-Item -Path WSMan:\localhost\Service\Auth\Basic -Value $false
+`
+
+// encodePowershellCommand encodes script for `powershell -EncodedCommand`,
+// which expects base64 encoded UTF-16LE
+func encodePowershellCommand(script string) string {
+	var buf []byte
+	for _, r := range utf16.Encode([]rune(script)) {
+		buf = append(buf, byte(r), byte(r>>8))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	bccbuilder "github.com/hashicorp/packer-plugin-baiducloud/builder/bcc"
+	"github.com/hashicorp/packer-plugin-baiducloud/provisioner/cloudassistant"
 	"github.com/hashicorp/packer-plugin-baiducloud/version"
 	"github.com/hashicorp/packer-plugin-sdk/plugin"
 )
@@ -13,6 +14,7 @@ import (
 func main() {
 	pps := plugin.NewSet()
 	pps.RegisterBuilder("bcc", new(bccbuilder.Builder))
+	pps.RegisterProvisioner("cloud-assistant", new(cloudassistant.Provisioner))
 	pps.SetVersion(version.PluginVersion)
 
 	err := pps.Run()
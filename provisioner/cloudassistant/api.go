@@ -0,0 +1,103 @@
+package cloudassistant
+
+import (
+	"github.com/baidubce/bce-sdk-go/bce"
+	"github.com/baidubce/bce-sdk-go/http"
+	"github.com/hashicorp/packer-plugin-baiducloud/builder/bcc"
+)
+
+// the uri of the action runs of cloud assistant
+const actionRunUri = "/v1/ca/actionRun"
+
+// the type of the command
+const (
+	commandTypeShell      = "SHELL"
+	commandTypePowerShell = "POWERSHELL"
+)
+
+// the states of an action run
+const (
+	runStatePending = "PENDING"
+	runStateRunning = "RUNNING"
+	runStateSuccess = "SUCCESS"
+	runStateFailed  = "FAILED"
+)
+
+type command struct {
+	Type     string `json:"type"`
+	Content  string `json:"content"`
+	Scope    string `json:"scope"`
+	ExecUser string `json:"execUser,omitempty"`
+	WorkDir  string `json:"workDir,omitempty"`
+}
+
+type action struct {
+	Type          string   `json:"type"`
+	Name          string   `json:"name"`
+	TimeoutSecond int      `json:"timeoutSecond"`
+	Command       *command `json:"command"`
+}
+
+type target struct {
+	InstanceType string `json:"instanceType"`
+	InstanceId   string `json:"instanceId"`
+}
+
+type runActionArgs struct {
+	Action             *action  `json:"action"`
+	TargetSelectorType string   `json:"targetSelectorType"`
+	Targets            []target `json:"targets"`
+}
+
+type runActionResult struct {
+	RunId string `json:"runId"`
+}
+
+type childRun struct {
+	Target       target `json:"target"`
+	State        string `json:"state"`
+	Output       string `json:"output"`
+	ExitCode     *int   `json:"exitCode"`
+	ErrorCode    string `json:"errorCode"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+type actionRun struct {
+	Id       string     `json:"id"`
+	State    string     `json:"state"`
+	Children []childRun `json:"children"`
+}
+
+type getActionRunResult struct {
+	ActionRun actionRun `json:"actionRun"`
+}
+
+// runCommand runs the command on the bcc instance through cloud assistant,
+// and returns the id of the action run
+func runCommand(client bce.Client, instanceId, name string, cmd *command, timeoutSecond int) (string, error) {
+	args := &runActionArgs{
+		Action: &action{
+			Type:          "COMMAND",
+			Name:          name,
+			TimeoutSecond: timeoutSecond,
+			Command:       cmd,
+		},
+		TargetSelectorType: "INSTANCES_LIST",
+		Targets:            []target{{InstanceType: "BCC", InstanceId: instanceId}},
+	}
+
+	result := &runActionResult{}
+	if err := bcc.SendRequest(client, http.POST, actionRunUri, nil, args, result); err != nil {
+		return "", err
+	}
+	return result.RunId, nil
+}
+
+// getActionRun returns the state and output of the action run
+func getActionRun(client bce.Client, runId string) (*actionRun, error) {
+	result := &getActionRunResult{}
+	if err := bcc.SendRequest(client, http.GET, actionRunUri+"/"+runId, nil, nil, result); err != nil {
+		return nil, err
+	}
+	return &result.ActionRun, nil
+}
@@ -0,0 +1,272 @@
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+//go:generate packer-sdc struct-markdown
+
+package cloudassistant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/baidubce/bce-sdk-go/bce"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-baiducloud/builder/bcc"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+)
+
+// the values of `command_type`
+const (
+	CommandTypeShell      = "shell"
+	CommandTypePowerShell = "powershell"
+)
+
+// pollInterval is the interval of polling the output of the command
+const pollInterval = 3 * time.Second
+
+// runGracePeriod is the time to wait for the action run beyond its timeout,
+// before giving up polling it
+const runGracePeriod = 2 * time.Minute
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	// Baiducloud access key, which defaults to the environment variable
+	// `BAIDUCLOUD_ACCESS_KEY`.
+	AccessKey string `mapstructure:"access_key" required:"false"`
+	// Baiducloud secret key, which defaults to the environment variable
+	// `BAIDUCLOUD_SECRET_KEY`.
+	SecretKey string `mapstructure:"secret_key" required:"false"`
+	// The region of the instance, which defaults to the environment variable
+	// `BAIDUCLOUD_REGION`, or the region of the `baiducloud-bcc` build.
+	Region string `mapstructure:"region" required:"false"`
+
+	// The commands to run, which are joined with newlines and run as a
+	// single script.
+	Inline []string `mapstructure:"inline" required:"false"`
+	// The path of a local script to run.
+	Script string `mapstructure:"script" required:"false"`
+	// The paths of local scripts to run in order. Exactly one of `inline`,
+	// `script` and `scripts` must be set.
+	Scripts []string `mapstructure:"scripts" required:"false"`
+	// The type of the commands, `shell` or `powershell`. Defaults to
+	// `shell`.
+	CommandType string `mapstructure:"command_type" required:"false"`
+	// The instance to run the commands on. Defaults to the instance of the
+	// build, so it only needs to be set when the provisioner isn't used
+	// with the `baiducloud-bcc` builder.
+	InstanceId string `mapstructure:"instance_id" required:"false"`
+	// The user to run the commands as. Defaults to `root` on linux and
+	// `Administrator` on windows, as decided by cloud assistant.
+	ExecUser string `mapstructure:"exec_user" required:"false"`
+	// The working directory of the commands.
+	WorkDir string `mapstructure:"work_dir" required:"false"`
+	// The timeout of each script, such as `10m`. Defaults to `30m`.
+	Timeout time.Duration `mapstructure:"timeout" required:"false"`
+	// The exit codes which are treated as success. Defaults to `[0]`.
+	ValidExitCodes []int `mapstructure:"valid_exit_codes" required:"false"`
+
+	ctx interpolate.Context
+}
+
+type Provisioner struct {
+	config Config
+	access bcc.BaiduCloudAccessConfig
+}
+
+func (p *Provisioner) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *Provisioner) Prepare(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         "baiducloud-cloud-assistant",
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	var errs *packersdk.MultiError
+	p.access = bcc.BaiduCloudAccessConfig{
+		BaiduCloudAccessKey: p.config.AccessKey,
+		BaiduCloudSecretKey: p.config.SecretKey,
+		BaiduCloudRegion:    p.config.Region,
+	}
+	// the region defaults to the one of the build, which is only known
+	// while provisioning, so it's only validated if it's set
+	if p.access.BaiduCloudRegion != "" || os.Getenv("BAIDUCLOUD_REGION") != "" {
+		errs = packersdk.MultiErrorAppend(errs, p.access.Prepare(&p.config.ctx)...)
+	} else if err := p.access.Config(); err != nil {
+		errs = packersdk.MultiErrorAppend(errs, err)
+	}
+
+	sources := 0
+	for _, set := range []bool{len(p.config.Inline) > 0, p.config.Script != "", len(p.config.Scripts) > 0} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("exactly one of 'inline', 'script' and 'scripts' must be specified"))
+	}
+	if p.config.Script != "" {
+		p.config.Scripts = []string{p.config.Script}
+	}
+	for _, path := range p.config.Scripts {
+		if _, err := os.Stat(path); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("bad script '%s': %s", path, err))
+		}
+	}
+
+	if p.config.CommandType == "" {
+		p.config.CommandType = CommandTypeShell
+	}
+	if p.config.CommandType != CommandTypeShell && p.config.CommandType != CommandTypePowerShell {
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("'command_type' must be '%s' or '%s'",
+			CommandTypeShell, CommandTypePowerShell))
+	}
+	if p.config.Timeout == 0 {
+		p.config.Timeout = 30 * time.Minute
+	}
+	if p.config.Timeout < time.Second {
+		errs = packersdk.MultiErrorAppend(errs, errors.New("'timeout' must be at least 1s"))
+	}
+	if len(p.config.ValidExitCodes) == 0 {
+		p.config.ValidExitCodes = []int{0}
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+	packersdk.LogSecretFilter.Set(p.access.BaiduCloudAccessKey, p.access.BaiduCloudSecretKey)
+	return nil
+}
+
+func (p *Provisioner) Provision(ctx context.Context, ui packersdk.Ui, _ packersdk.Communicator, generatedData map[string]interface{}) error {
+	instanceId := p.config.InstanceId
+	if instanceId == "" {
+		// the builder puts the instance id into the generated data as `ID`
+		if id, ok := generatedData["ID"].(string); ok && strings.HasPrefix(id, "i-") {
+			instanceId = id
+		}
+	}
+	if instanceId == "" {
+		return errors.New("'instance_id' must be specified, since the instance of the build is unknown")
+	}
+	if p.access.BaiduCloudRegion == "" {
+		if region, ok := generatedData["Region"].(string); ok {
+			p.access.BaiduCloudRegion = region
+		}
+	}
+	if p.access.BaiduCloudRegion == "" {
+		return errors.New("region option or BAIDUCLOUD_REGION must be provided in template file or environment variables")
+	}
+
+	client, err := p.access.Client()
+	if err != nil {
+		return err
+	}
+
+	if len(p.config.Inline) > 0 {
+		ui.Say(fmt.Sprintf("Running inline commands on instance %s through cloud assistant...", instanceId))
+		return p.run(ctx, ui, client, instanceId, "packer-inline", strings.Join(p.config.Inline, "\n"))
+	}
+	for _, path := range p.config.Scripts {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("Failed to read script %s: %s", path, err)
+		}
+		ui.Say(fmt.Sprintf("Running script %s on instance %s through cloud assistant...", path, instanceId))
+		if err := p.run(ctx, ui, client, instanceId, "packer-script", string(content)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run runs the command, streams its output and checks its exit code
+func (p *Provisioner) run(ctx context.Context, ui packersdk.Ui, client bce.Client, instanceId, name, content string) error {
+	cmd := &command{
+		Type:     commandTypeShell,
+		Content:  content,
+		Scope:    "INDIVIDUAL",
+		ExecUser: p.config.ExecUser,
+		WorkDir:  p.config.WorkDir,
+	}
+	if p.config.CommandType == CommandTypePowerShell {
+		cmd.Type = commandTypePowerShell
+	}
+	runId, err := runCommand(client, instanceId, name, cmd, int(p.config.Timeout.Seconds()))
+	if err != nil {
+		return fmt.Errorf("Failed to run command through cloud assistant: %s", err)
+	}
+	ui.Message(fmt.Sprintf("Started action run %s", runId))
+
+	// the action run should end by its timeout, unless it never reaches
+	// the instance
+	deadline := time.After(p.config.Timeout + runGracePeriod)
+	printed := 0
+	for {
+		run, err := getActionRun(client, runId)
+		if err != nil {
+			return fmt.Errorf("Failed to get action run(%s): %s", runId, err)
+		}
+		var child *childRun
+		for i := range run.Children {
+			if run.Children[i].Target.InstanceId == instanceId {
+				child = &run.Children[i]
+			}
+		}
+		if child != nil {
+			// the output grows while the command is running
+			if len(child.Output) > printed {
+				ui.Message(strings.TrimRight(child.Output[printed:], "\n"))
+				printed = len(child.Output)
+			}
+			switch child.State {
+			case runStatePending, runStateRunning:
+			case runStateSuccess:
+				return p.checkExitCode(runId, child)
+			case runStateFailed:
+				// the command may fail to be dispatched, e.g. when the
+				// agent is offline, in which case it isn't executed at all
+				if child.ErrorCode != "" || child.ExitCode == nil {
+					return fmt.Errorf("The action run(%s) failed: %s %s", runId, child.ErrorCode, child.ErrorMessage)
+				}
+				return p.checkExitCode(runId, child)
+			default:
+				return fmt.Errorf("The action run(%s) ended in state %s: %s %s",
+					runId, child.State, child.ErrorCode, child.ErrorMessage)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline:
+			return fmt.Errorf("The action run(%s) didn't end on instance %s within %s", runId, instanceId, p.config.Timeout+runGracePeriod)
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// checkExitCode returns an error unless the exit code is a valid one. A
+// successful run without exit code is treated as exit code 0.
+func (p *Provisioner) checkExitCode(runId string, child *childRun) error {
+	exitCode := 0
+	if child.ExitCode != nil {
+		exitCode = *child.ExitCode
+	}
+	for _, code := range p.config.ValidExitCodes {
+		if exitCode == code {
+			return nil
+		}
+	}
+	return fmt.Errorf("The action run(%s) exited with code %d: %s", runId, exitCode, child.ErrorMessage)
+}
@@ -0,0 +1,164 @@
+package cloudassistant
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baidubce/bce-sdk-go/bce"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"access_key": "ak",
+		"secret_key": "sk",
+		"inline":     []interface{}{"echo foo"},
+	}
+}
+
+func TestProvisionerPrepare_Defaults(t *testing.T) {
+	var p Provisioner
+	if err := p.Prepare(testConfig()); err != nil {
+		t.Fatalf("Shouldn't raise error: %s", err)
+	}
+	if p.config.CommandType != CommandTypeShell {
+		t.Fatalf("Bad command type: %s", p.config.CommandType)
+	}
+	if p.config.Timeout != 30*time.Minute {
+		t.Fatalf("Bad timeout: %s", p.config.Timeout)
+	}
+	if len(p.config.ValidExitCodes) != 1 || p.config.ValidExitCodes[0] != 0 {
+		t.Fatalf("Bad valid exit codes: %v", p.config.ValidExitCodes)
+	}
+}
+
+func TestProvisionerPrepare_Sources(t *testing.T) {
+	f, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+	defer os.Remove(f.Name())
+	f.Close()
+
+	config := testConfig()
+	config["script"] = f.Name()
+	var p Provisioner
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("Should raise an error")
+	}
+
+	delete(config, "inline")
+	p = Provisioner{}
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("Shouldn't raise error: %s", err)
+	}
+	if len(p.config.Scripts) != 1 || p.config.Scripts[0] != f.Name() {
+		t.Fatalf("Bad scripts: %v", p.config.Scripts)
+	}
+
+	config["script"] = f.Name() + ".missing"
+	p = Provisioner{}
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("Should raise an error")
+	}
+}
+
+func TestProvisionerPrepare_CommandType(t *testing.T) {
+	config := testConfig()
+	config["command_type"] = CommandTypePowerShell
+	var p Provisioner
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("Shouldn't raise error: %s", err)
+	}
+
+	config["command_type"] = "bash"
+	p = Provisioner{}
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("Should raise an error")
+	}
+}
+
+func TestProvisionerPrepare_Region(t *testing.T) {
+	config := testConfig()
+	config["region"] = "fwh"
+	var p Provisioner
+	if err := p.Prepare(config); err != nil {
+		t.Fatalf("Shouldn't raise error: %s", err)
+	}
+
+	config["region"] = "unknown"
+	p = Provisioner{}
+	if err := p.Prepare(config); err == nil {
+		t.Fatal("Should raise an error")
+	}
+}
+
+func TestProvisionerCheckExitCode(t *testing.T) {
+	exitCode := func(code int) *int { return &code }
+	p := Provisioner{config: Config{ValidExitCodes: []int{0, 3010}}}
+	if err := p.checkExitCode("r-xxx", &childRun{ExitCode: exitCode(3010)}); err != nil {
+		t.Fatalf("Shouldn't raise error: %s", err)
+	}
+	if err := p.checkExitCode("r-xxx", &childRun{ExitCode: exitCode(1)}); err == nil {
+		t.Fatal("Should raise an error")
+	}
+	if err := p.checkExitCode("r-xxx", &childRun{}); err != nil {
+		t.Fatalf("Shouldn't raise error without exit code: %s", err)
+	}
+}
+
+// testActionRunServer serves the action run api, and returns the child run
+// as the state of the action run on instance i-xxx
+func testActionRunServer(t *testing.T, child string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == actionRunUri:
+			fmt.Fprint(w, `{"runId": "r-xxx"}`)
+		case r.Method == http.MethodGet && r.URL.Path == actionRunUri+"/r-xxx":
+			fmt.Fprintf(w, `{"actionRun": {"id": "r-xxx", "children": [%s]}}`, child)
+		default:
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestProvisionerRun_FailedToDispatch(t *testing.T) {
+	server := testActionRunServer(t, `{"target": {"instanceId": "i-xxx"}, "state": "FAILED",
+		"exitCode": 0, "errorCode": "AGENT_OFFLINE", "errorMessage": "agent is offline"}`)
+	defer server.Close()
+	client, err := bce.NewBceClientWithAkSk("ak", "sk", server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create client: %s", err)
+	}
+
+	p := Provisioner{config: Config{Timeout: time.Minute, ValidExitCodes: []int{0}}}
+	err = p.run(context.Background(), packersdk.TestUi(t), client, "i-xxx", "packer-inline", "echo foo")
+	if err == nil || !strings.Contains(err.Error(), "AGENT_OFFLINE") {
+		t.Fatalf("Should raise the dispatch error: %v", err)
+	}
+}
+
+func TestProvisionerRun_Success(t *testing.T) {
+	server := testActionRunServer(t, `{"target": {"instanceId": "i-xxx"}, "state": "SUCCESS",
+		"output": "foo\n", "exitCode": 0}`)
+	defer server.Close()
+	client, err := bce.NewBceClientWithAkSk("ak", "sk", server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create client: %s", err)
+	}
+
+	p := Provisioner{config: Config{Timeout: time.Minute, ValidExitCodes: []int{0}}}
+	err = p.run(context.Background(), packersdk.TestUi(t), client, "i-xxx", "packer-inline", "echo foo")
+	if err != nil {
+		t.Fatalf("Shouldn't raise error: %s", err)
+	}
+}